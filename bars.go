@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/chart"
+	"github.com/piquette/finance-go/datetime"
+)
+
+// maxBarsWindow caps the window served by /api/bars to bound payload size
+const maxBarsWindow = 366 * 24 * time.Hour
+
+// Bar is a daily OHLCV bar. Prices are decimal strings to avoid float precision loss.
+type Bar struct {
+	Date     string `json:"date"`
+	Open     string `json:"open"`
+	High     string `json:"high"`
+	Low      string `json:"low"`
+	Close    string `json:"close"`
+	AdjClose string `json:"adj_close"`
+	Volume   int    `json:"volume"`
+}
+
+// getBars fetches the daily bars for ticker between start and end
+func getBars(ctx context.Context, ticker string, start, end time.Time) ([]Bar, error) {
+	if end.Sub(start) > maxBarsWindow {
+		return nil, fmt.Errorf("%w: window exceeds %d days", ErrInvalidRange, int(maxBarsWindow.Hours()/24))
+	}
+
+	params := &chart.Params{
+		Params:   finance.Params{Context: &ctx},
+		Symbol:   ticker,
+		Start:    datetime.FromUnix(int(start.Unix())),
+		End:      datetime.FromUnix(int(end.AddDate(0, 0, 1).Unix())), // end is inclusive
+		Interval: datetime.OneDay,
+	}
+
+	iter := chart.Get(params)
+	var bars []Bar
+	for iter.Next() {
+		b := iter.Bar()
+		bars = append(bars, Bar{
+			Date:     time.Unix(int64(b.Timestamp), 0).In(cfg.Location).Format("2006-01-02"),
+			Open:     b.Open.String(),
+			High:     b.High.String(),
+			Low:      b.Low.String(),
+			Close:    b.Close.String(),
+			AdjClose: b.AdjClose.String(),
+			Volume:   b.Volume,
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error fetching bars for %s: %v", ticker, err)
+	}
+	return bars, nil
+}
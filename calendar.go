@@ -0,0 +1,190 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ------------------------------------
+// NYSE trading calendar
+// ------------------------------------
+
+// isTradingDay reports whether the NYSE is open on t's calendar date
+func isTradingDay(t time.Time) bool {
+	switch t.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	return !isMarketHoliday(t)
+}
+
+// isMarketHoliday reports whether t's calendar date is a full-day NYSE holiday
+func isMarketHoliday(t time.Time) bool {
+	y, m, d := t.Date()
+	for _, h := range marketHolidays(y) {
+		hy, hm, hd := h.Date()
+		if hy == y && hm == m && hd == d {
+			return true
+		}
+	}
+	return false
+}
+
+// marketHolidays returns the observed full-day NYSE holidays for a year
+func marketHolidays(year int) []time.Time {
+	date := func(m time.Month, d int) time.Time {
+		return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	holidays := []time.Time{
+		nthWeekday(year, time.January, time.Monday, 3),    // Martin Luther King Jr. Day
+		nthWeekday(year, time.February, time.Monday, 3),   // Washington's Birthday
+		easterSunday(year).AddDate(0, 0, -2),              // Good Friday
+		lastWeekday(year, time.May, time.Monday),          // Memorial Day
+		observed(date(time.July, 4)),                      // Independence Day
+		nthWeekday(year, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(year, time.November, time.Thursday, 4), // Thanksgiving
+		observed(date(time.December, 25)),                 // Christmas
+	}
+
+	// New Year's Day is not observed on the prior Friday when it falls on a Saturday
+	if newYear := date(time.January, 1); newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, observed(newYear))
+	}
+	if year >= 2022 {
+		holidays = append(holidays, observed(date(time.June, 19))) // Juneteenth
+	}
+	return holidays
+}
+
+// observed shifts a Saturday holiday to Friday and a Sunday holiday to Monday
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// nthWeekday returns the nth occurrence of weekday in the month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	t := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(t.Weekday()) + 7) % 7
+	return t.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last occurrence of weekday in the month
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	t := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(t.Weekday()) - int(weekday) + 7) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// easterSunday computes Western Easter using the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// tradingDaysBack returns the trading day n sessions before the last trading
+// day on or before end, so the window [result, end] spans n daily moves
+func tradingDaysBack(end time.Time, n int) time.Time {
+	t := end
+	for !isTradingDay(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, -1)
+		if isTradingDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// tradingDaysBetween counts the trading days from start through end, inclusive
+func tradingDaysBetween(start, end time.Time) int {
+	n := 0
+	for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+		if isTradingDay(t) {
+			n++
+		}
+	}
+	return n
+}
+
+// lastTradingDay returns the last trading day on or before the earlier of end and now
+func lastTradingDay(end, now time.Time) time.Time {
+	loc := end.Location()
+	t := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	now = now.In(loc)
+	if today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc); today.Before(t) {
+		t = today
+	}
+	for !isTradingDay(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// staleDays returns how many calendar days the last bar's date trails expected (0 if not behind)
+func staleDays(lastBar, expected time.Time) int {
+	if lastBar.IsZero() {
+		return 0
+	}
+	loc := expected.Location()
+	lastBar = lastBar.In(loc)
+	lastDay := time.Date(lastBar.Year(), lastBar.Month(), lastBar.Day(), 0, 0, 0, 0, loc)
+	if !lastDay.Before(expected) {
+		return 0
+	}
+	// Round so a DST change in between doesn't drop a day
+	return int(math.Round(expected.Sub(lastDay).Hours() / 24))
+}
+
+// missedSessions counts the trading days strictly between the dates of prev
+// and t: the sessions with no bar that forward-filling repeats prev's close on
+func missedSessions(prev, t time.Time) int {
+	from := time.Date(prev.Year(), prev.Month(), prev.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	to := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	return tradingDaysBetween(from, to)
+}
+
+// forwardFill aligns closes (one per bar, at times) to the trading calendar,
+// repeating the previous close on trading days that have no bar. Bars on
+// non-trading days are kept as-is.
+func forwardFill(times []time.Time, closes []decimal.Decimal) []decimal.Decimal {
+	if len(times) == 0 {
+		return closes
+	}
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	filled := make([]decimal.Decimal, 0, len(closes))
+	next := day(times[0])
+	for i, t := range times {
+		for d := day(t); next.Before(d); next = next.AddDate(0, 0, 1) {
+			if isTradingDay(next) {
+				filled = append(filled, filled[len(filled)-1])
+			}
+		}
+		filled = append(filled, closes[i])
+		next = day(t).AddDate(0, 0, 1)
+	}
+	return filled
+}
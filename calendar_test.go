@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// date parses a 2006-01-02 date at midnight in cfg.Location
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", s, cfg.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMissedSessions(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev, at string
+		want     int
+	}{
+		{name: "same day", prev: "2025-09-03", at: "2025-09-03", want: 0},
+		{name: "next session", prev: "2025-09-03", at: "2025-09-04", want: 0},
+		{name: "over a weekend", prev: "2025-09-05", at: "2025-09-08", want: 0},
+		{name: "over Labor Day", prev: "2025-08-29", at: "2025-09-02", want: 0},
+		{name: "halted two sessions", prev: "2025-09-09", at: "2025-09-12", want: 2},
+		{name: "halted over a weekend", prev: "2025-09-11", at: "2025-09-16", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missedSessions(date(t, tt.prev), date(t, tt.at)); got != tt.want {
+				t.Errorf("missedSessions(%s, %s) = %d, want %d", tt.prev, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardFill(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []string
+		want  []int64
+	}{
+		{name: "empty"},
+		{name: "no gaps", dates: []string{"2025-09-04", "2025-09-05", "2025-09-08"}, want: []int64{1, 2, 3}},
+		{name: "one missing session", dates: []string{"2025-09-09", "2025-09-11"}, want: []int64{1, 1, 2}},
+		{name: "gap over a weekend", dates: []string{"2025-09-11", "2025-09-16"}, want: []int64{1, 1, 1, 2}},
+		{name: "holiday is not filled", dates: []string{"2025-08-29", "2025-09-02"}, want: []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var times []time.Time
+			var closes []decimal.Decimal
+			for i, d := range tt.dates {
+				times = append(times, date(t, d).Add(16*time.Hour))
+				closes = append(closes, decimal.NewFromInt(int64(i+1)))
+			}
+			var got []int64
+			for _, c := range forwardFill(times, closes) {
+				got = append(got, c.IntPart())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("forwardFill = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		date string
+		want bool
+		why  string
+	}{
+		{"2025-01-01", false, "New Year's Day"},
+		{"2025-01-20", false, "Martin Luther King Jr. Day"},
+		{"2025-02-17", false, "Washington's Birthday"},
+		{"2025-04-18", false, "Good Friday"},
+		{"2025-05-26", false, "Memorial Day"},
+		{"2025-06-19", false, "Juneteenth"},
+		{"2025-07-04", false, "Independence Day"},
+		{"2025-09-01", false, "Labor Day"},
+		{"2025-11-27", false, "Thanksgiving"},
+		{"2025-12-25", false, "Christmas"},
+		{"2021-07-05", false, "Independence Day on a Sunday, observed Monday"},
+		{"2021-12-24", false, "Christmas on a Saturday, observed Friday"},
+		{"2022-12-26", false, "Christmas on a Sunday, observed Monday"},
+		{"2021-12-31", true, "New Year's Day on a Saturday is not observed the Friday before"},
+		{"2021-06-18", true, "Juneteenth before 2022"},
+		{"2025-11-28", true, "day after Thanksgiving (early close)"},
+		{"2025-09-02", true, "Tuesday after Labor Day"},
+		{"2025-09-06", false, "Saturday"},
+		{"2025-09-07", false, "Sunday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			if got := isTradingDay(date(t, tt.date)); got != tt.want {
+				t.Errorf("isTradingDay(%s) = %t, want %t (%s)", tt.date, got, tt.want, tt.why)
+			}
+		})
+	}
+}
+
+func TestWindowTimeZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10pm on Labor Day in New York is already Tuesday in UTC
+	now := time.Date(2025, 9, 2, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		loc       *time.Location
+		wantStart string // Window start as a UTC instant
+		wantLast  string // Last trading day the window has reached as of now
+	}{
+		{name: "New York", loc: newYork, wantStart: "2025-09-01T04:00:00Z", wantLast: "2025-08-29"},
+		{name: "UTC", loc: time.UTC, wantStart: "2025-09-01T00:00:00Z", wantLast: "2025-09-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.Location = tt.loc })
+			start, end := getMonthRange(2025, time.September, 1)
+			if got := start.UTC().Format(time.RFC3339); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := lastTradingDay(end, now).Format("2006-01-02"); got != tt.wantLast {
+				t.Errorf("last trading day = %s, want %s", got, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestTradingDaysBetween(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		want       int
+	}{
+		{name: "September, Labor Day", start: "2025-09-01", end: "2025-09-30", want: 21},
+		{name: "November, Thanksgiving", start: "2025-11-01", end: "2025-11-30", want: 19},
+		{name: "July, Independence Day", start: "2025-07-01", end: "2025-07-31", want: 22},
+		{name: "February, Presidents Day", start: "2025-02-01", end: "2025-02-28", want: 19},
+		{name: "holiday alone", start: "2025-09-01", end: "2025-09-01", want: 0},
+		{name: "weekend", start: "2025-09-06", end: "2025-09-07", want: 0},
+		{name: "single session", start: "2025-09-02", end: "2025-09-02", want: 1},
+		{name: "reversed", start: "2025-09-30", end: "2025-09-01", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tradingDaysBetween(date(t, tt.start), date(t, tt.end)); got != tt.want {
+				t.Errorf("tradingDaysBetween(%s, %s) = %d, want %d", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
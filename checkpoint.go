@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkpointInterval is how many newly fetched results trigger a checkpoint write
+const checkpointInterval = 25
+
+// checkpoint is the on-disk record of tickers already fetched for a window
+type checkpoint struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Key     string    `json:"key"` // See checkpointKey
+	Results []Result  `json:"results"`
+}
+
+// resultFilters are the thresholds a run rejects or holds back fetched
+// results by. Resumed results skip them, so they are part of checkpointKey.
+type resultFilters struct {
+	MinPrice     float64
+	MinVolume    float64
+	StaleMaxDays int
+	MaxAbsReturn float64
+}
+
+// checkpointKey identifies the runs a checkpoint may resume: the index and a
+// hash of everything that changes a fetched result (return type and basis,
+// baseline, periods, metrics, fetch mode, target currency and so on) or which
+// results are kept, so a resumed run never mixes in results computed or
+// filtered another way
+func checkpointKey(index, fetchMode, targetCurrency string, fopts FetchOptions, filters resultFilters) string {
+	data, err := json.Marshal(struct {
+		FetchMode      string
+		TargetCurrency string
+		Fetch          FetchOptions
+		Filters        resultFilters
+	}{fetchMode, targetCurrency, fopts, filters})
+	if err != nil {
+		data = []byte(fmt.Sprintf("%s %s %+v %+v", fetchMode, targetCurrency, fopts, filters))
+	}
+	sum := sha256.Sum256(data)
+	if index == "" {
+		index = "custom"
+	}
+	return strings.ReplaceAll(index, ",", "+") + "_" + hex.EncodeToString(sum[:6])
+}
+
+// checkpointPath returns the temp file used to checkpoint the given window and key
+func checkpointPath(start, end time.Time, key string) string {
+	name := fmt.Sprintf("omaha_checkpoint_%s_%s_%s.json", start.Format("20060102"), end.Format("20060102"), key)
+	return filepath.Join(os.TempDir(), name)
+}
+
+// loadCheckpoint returns the results saved for the window and key, or nil if there is no checkpoint
+func loadCheckpoint(start, end time.Time, key string) ([]Result, error) {
+	data, err := os.ReadFile(checkpointPath(start, end, key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if !cp.Start.Equal(start) || !cp.End.Equal(end) || cp.Key != key {
+		return nil, nil
+	}
+	return cp.Results, nil
+}
+
+// saveCheckpoint writes the results to the window's checkpoint file for key.
+// It writes to a temp file first so a crash never leaves a partial checkpoint.
+func saveCheckpoint(start, end time.Time, key string, results []Result) error {
+	data, err := json.Marshal(checkpoint{Start: start, End: end, Key: key, Results: results})
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+
+	path := checkpointPath(start, end, key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeCheckpoint deletes the window's checkpoint for key after a successful run
+func removeCheckpoint(start, end time.Time, key string) {
+	if err := os.Remove(checkpointPath(start, end, key)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("⚠️  Failed to remove checkpoint: %v\n", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// defaultFetchOptions are the FetchOptions of a run with default options
+// under the config newFakeRun leaves in place
+func defaultFetchOptions() FetchOptions {
+	return FetchOptions{
+		ReturnType:  returnSimple,
+		ReturnBasis: basisClose,
+		PaddingDays: cfg.FetchPaddingDays,
+		Baseline:    baselineFirstInWindow,
+		Metrics:     allMetrics,
+	}
+}
+
+// defaultResultFilters are the resultFilters of a run with default options
+func defaultResultFilters() resultFilters {
+	return resultFilters{MinPrice: cfg.MinPrice, MinVolume: cfg.MinAvgVolume, StaleMaxDays: cfg.StaleMaxDays, MaxAbsReturn: cfg.MaxAbsReturn}
+}
+
+func TestCheckpointKey(t *testing.T) {
+	base := checkpointKey("sp500", "", "", defaultFetchOptions(), defaultResultFilters())
+	tests := []struct {
+		name     string
+		index    string
+		mode     string
+		currency string
+		change   func(*FetchOptions)
+		filter   func(*resultFilters)
+		same     bool
+	}{
+		{name: "same options", index: "sp500", same: true},
+		{name: "other index", index: "sp400"},
+		{name: "quote mode", index: "sp500", mode: fetchQuote},
+		{name: "target currency", index: "sp500", currency: "EUR"},
+		{name: "log returns", index: "sp500", change: func(o *FetchOptions) { o.ReturnType = returnLog }},
+		{name: "vwap basis", index: "sp500", change: func(o *FetchOptions) { o.ReturnBasis = basisVWAP }},
+		{name: "prior-close baseline", index: "sp500", change: func(o *FetchOptions) { o.Baseline = baselinePriorClose }},
+		{name: "padding", index: "sp500", change: func(o *FetchOptions) { o.PaddingDays = 5 }},
+		{name: "forward fill", index: "sp500", change: func(o *FetchOptions) { o.ForwardFill = true }},
+		{name: "metrics", index: "sp500", change: func(o *FetchOptions) { o.Metrics = MetricSet{} }},
+		{name: "periods", index: "sp500", change: func(o *FetchOptions) {
+			o.PeriodStarts = map[string]time.Time{"ytd": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		}},
+		{name: "min price", index: "sp500", filter: func(f *resultFilters) { f.MinPrice = 5 }},
+		{name: "min volume", index: "sp500", filter: func(f *resultFilters) { f.MinVolume = 1e6 }},
+		{name: "stale limit", index: "sp500", filter: func(f *resultFilters) { f.StaleMaxDays = 3 }},
+		{name: "max abs return", index: "sp500", filter: func(f *resultFilters) { f.MaxAbsReturn = 0.5 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fopts := defaultFetchOptions()
+			if tt.change != nil {
+				tt.change(&fopts)
+			}
+			filters := defaultResultFilters()
+			if tt.filter != nil {
+				tt.filter(&filters)
+			}
+			if got := checkpointKey(tt.index, tt.mode, tt.currency, fopts, filters); (got == base) != tt.same {
+				t.Errorf("key %s vs base %s: same = %t, want %t", got, base, got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	start, end := septemberStart, septemberEnd
+	saved := []Result{{Ticker: "AAA", Sector: "Tech", Return: 0.1}}
+	if err := saveCheckpoint(start, end, "k1", saved); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		key        string
+		want       int
+	}{
+		{name: "same window and key", start: start, end: end, key: "k1", want: 1},
+		{name: "other key", start: start, end: end, key: "k2", want: 0},
+		{name: "other window", start: start.AddDate(0, -1, 0), end: start, key: "k1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadCheckpoint(tt.start, tt.end, tt.key)
+			if err != nil || len(got) != tt.want {
+				t.Errorf("loadCheckpoint = %d results, %v; want %d", len(got), err, tt.want)
+			}
+		})
+	}
+
+	removeCheckpoint(start, end, "k1")
+	if got, err := loadCheckpoint(start, end, "k1"); err != nil || got != nil {
+		t.Errorf("after remove: %v, %v", got, err)
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        RunOptions
+		wantResumed bool
+	}{
+		{name: "same options resume", wantResumed: true},
+		{name: "log returns re-fetch", opts: RunOptions{ReturnType: returnLog}},
+		{name: "fresh re-fetches", opts: RunOptions{Fresh: true}},
+		{name: "stricter min price re-fetches", opts: RunOptions{MinPrice: 110}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			price := func(i int) float64 { return 100 + float64(i) }
+			f.charts["AAA"] = dailyBars("2025-09-01", "2025-09-30", price)
+			f.charts["BBB"] = dailyBars("2025-09-01", "2025-09-30", price)
+
+			// A checkpoint for the default options, from an interrupted run
+			key := checkpointKey("", "", "", defaultFetchOptions(), defaultResultFilters())
+			checkpointed := []Result{{Ticker: "AAA", Sector: "Tech", Return: 0.5}}
+			if err := saveCheckpoint(septemberStart, septemberEnd, key, checkpointed); err != nil {
+				t.Fatal(err)
+			}
+
+			results, _, err := runFake(t, []string{"AAA", "BBB"}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resumed := f.chartCalls("AAA") == 0
+			if resumed != tt.wantResumed {
+				t.Errorf("AAA resumed = %t, want %t", resumed, tt.wantResumed)
+			}
+			if got := resultFor(t, results, "AAA").Return; (got == 0.5) != tt.wantResumed {
+				t.Errorf("AAA return = %v, resumed = %t", got, tt.wantResumed)
+			}
+			if f.chartCalls("BBB") != 1 {
+				t.Errorf("BBB fetched %d times, want 1", f.chartCalls("BBB"))
+			}
+		})
+	}
+}
+
+func TestResumeAppliesStricterFilters(t *testing.T) {
+	f := newFakeRun(t)
+	f.charts["AAA"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+
+	// An interrupted run with the default filters kept AAA at a close of 120
+	key := checkpointKey("", "", "", defaultFetchOptions(), defaultResultFilters())
+	checkpointed := []Result{{Ticker: "AAA", Sector: "Tech", Return: 0.2, LastClose: "120"}}
+	if err := saveCheckpoint(septemberStart, septemberEnd, key, checkpointed); err != nil {
+		t.Fatal(err)
+	}
+
+	// A run rejecting closes below 150 must not let it back in
+	results, summary, err := runFake(t, []string{"AAA"}, RunOptions{MinPrice: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 || len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0].Message, "below") {
+		t.Errorf("results %+v, errors %+v; want AAA rejected below the min price", results, summary.Errors)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// Process exit codes for cli mode, so cron alerting can tell failures apart
+const (
+	exitOK            = 0 // Run completed within the failure threshold
+	exitError         = 1 // Unexpected error
+	exitUsage         = 2 // Invalid flags or arguments
+	exitScrapeFailure = 3 // Ticker universe could not be scraped
+	exitDataFailure   = 4 // Too many tickers failed or no data was returned
+)
+
+// CLIOptions holds the cli-only outputs of a run
+type CLIOptions struct {
+	Snapshot string // Earlier run's JSON or CSV to diff the results against
+	Report   string // Path of a standalone HTML report to write
+}
+
+// cliWindow checks the -year, -month and -day flags like the HTTP window
+// parameters. With neither -year nor -month the run covers the previous
+// month, so -day's default of 1 only counts when it was given explicitly.
+func cliWindow(year, month, day int, daySet bool) (int, time.Month, int, error) {
+	if year == 0 && month == 0 && !daySet {
+		day = 0
+	}
+	if err := validateWindow(year, month, day); err != nil {
+		return 0, 0, 0, err
+	}
+	return year, time.Month(month), day, nil
+}
+
+// runCLI performs a single refresh and returns the process exit code
+func runCLI(year int, month time.Month, day int, opts RunOptions, copts CLIOptions) int {
+	var snapshot []Result
+	if copts.Snapshot != "" {
+		var err error
+		if snapshot, err = loadSnapshot(copts.Snapshot); err != nil {
+			log.Printf("❌ Invalid -baseline: %v", err)
+			return exitUsage
+		}
+	}
+
+	results, summary, err := getMTDResults(year, month, day, opts)
+	if err != nil {
+		log.Printf("❌ Run failed: %v", err)
+		if errors.Is(err, ErrScrape) {
+			return exitScrapeFailure
+		}
+		if errors.Is(err, ErrInvalidRange) {
+			return exitUsage
+		}
+		return exitError
+	}
+
+	if len(results) == 0 {
+		log.Printf("❌ Run returned no results (%d tickers failed)", summary.Failed)
+		return exitDataFailure
+	}
+	if rate := summary.FailureRate(); rate > cfg.MaxFailureRate {
+		log.Printf("❌ Failure rate %.1f%% exceeds threshold %.1f%% (%d/%d tickers failed)",
+			rate*100, cfg.MaxFailureRate*100, summary.Failed, summary.Tickers)
+		return exitDataFailure
+	}
+
+	if copts.Snapshot != "" {
+		logSnapshotDiff(diffResults(snapshot, results), cfg.SummaryCount)
+	}
+	if copts.Report != "" {
+		if err := writeHTMLReport(copts.Report, results, summary); err != nil {
+			log.Printf("Warning: %v", err)
+		} else {
+			log.Printf("✅ Saved report to %s\n", copts.Report)
+		}
+	}
+
+	log.Printf("✅ Run completed: %d fetched, %d failed", summary.Fetched, summary.Failed)
+	return exitOK
+}
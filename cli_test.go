@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCLIWindow(t *testing.T) {
+	tests := []struct {
+		name             string
+		year, month, day int
+		daySet           bool
+		wantMonth        time.Month
+		wantDay          int
+		wantErr          bool
+	}{
+		{name: "previous month", day: 1},
+		{name: "year and month", year: 2024, month: 3, day: 1, wantMonth: time.March, wantDay: 1},
+		{name: "explicit day", year: 2024, month: 3, day: 15, daySet: true, wantMonth: time.March, wantDay: 15},
+		{name: "month 13", year: 2024, month: 13, day: 1, wantErr: true},
+		{name: "day 40", year: 2024, month: 3, day: 40, daySet: true, wantErr: true},
+		{name: "Feb 30", year: 2024, month: 2, day: 30, daySet: true, wantErr: true},
+		{name: "month without a year", month: 3, day: 1, wantErr: true},
+		{name: "day alone", day: 5, daySet: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			year, month, day, err := cliWindow(tt.year, tt.month, tt.day, tt.daySet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && (year != tt.year || month != tt.wantMonth || day != tt.wantDay) {
+				t.Errorf("window %d-%d-%d, want %d-%d-%d", year, month, day, tt.year, tt.wantMonth, tt.wantDay)
+			}
+		})
+	}
+}
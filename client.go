@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/gocolly/colly"
+	finance "github.com/piquette/finance-go"
+	"golang.org/x/net/publicsuffix"
+)
+
+// configureFinanceClient installs the HTTP client used for all finance-go calls.
+// c.FetchTimeout bounds each request, so a stuck connection can't hang a worker
+// indefinitely, and the pooled transport keeps enough idle connections to Yahoo
+// for every worker to reuse one. It must run before the first finance-go
+// request, which caches the client.
+func configureFinanceClient(c Config) {
+	// Yahoo requires a cookie jar to obtain the crumb used on every request
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		log.Fatalf("Failed to create cookie jar: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = outboundProxy(c)
+	transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	if transport.MaxIdleConns < c.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = c.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = c.IdleConnTimeout
+	transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+
+	finance.SetHTTPClient(&http.Client{
+		Jar:       jar,
+		Timeout:   c.FetchTimeout,
+		Transport: transport,
+	})
+}
+
+// outboundProxy returns the proxy selector for outbound requests: c.ProxyURL
+// when set, otherwise HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment
+func outboundProxy(c Config) func(*http.Request) (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil || u.Host == "" {
+		log.Printf("Warning: invalid OMAHA_PROXY_URL=%q, using the environment's proxy settings", c.ProxyURL)
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(u)
+}
+
+// scrapeTransport is the transport shared by every Wikipedia scrape. It
+// also serves file:// URLs, for scraping a saved snapshot of a page.
+var scrapeTransport = sync.OnceValue(func() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = outboundProxy(cfg)
+	transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	return transport
+})
+
+// webhookClient is the client shared by webhook notifications. It goes
+// through the outbound proxy like every other request, and webhookTimeout
+// bounds each delivery.
+var webhookClient = sync.OnceValue(func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = outboundProxy(cfg)
+	return &http.Client{Timeout: webhookTimeout, Transport: transport}
+})
+
+// newCollector returns a colly collector that sends its requests through
+// scrapeTransport. Colly has no context support of its own, so every request
+// is bound to ctx, and cancelling ctx aborts a Visit in flight.
+func newCollector(ctx context.Context) *colly.Collector {
+	c := colly.NewCollector()
+	c.WithTransport(contextTransport{ctx: ctx, base: scrapeTransport()})
+	c.SetRequestTimeout(cfg.ScrapeTimeout)
+	return c
+}
+
+// contextTransport sends each request with ctx in place of the request's own context
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
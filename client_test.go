@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutboundProxy(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://query1.finance.yahoo.com/v8/finance/chart/AAPL", nil)
+	fromEnv, envErr := http.ProxyFromEnvironment(req)
+	tests := []struct {
+		name     string
+		proxyURL string
+		want     string // Empty means the environment's proxy
+	}{
+		{name: "configured", proxyURL: "http://proxy.internal:3128", want: "http://proxy.internal:3128"},
+		{name: "configured with credentials", proxyURL: "http://user:pw@proxy.internal:3128", want: "http://user:pw@proxy.internal:3128"},
+		{name: "unset uses the environment", proxyURL: ""},
+		{name: "invalid uses the environment", proxyURL: "not a url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outboundProxy(Config{ProxyURL: tt.proxyURL})(req)
+			if tt.want != "" {
+				if err != nil || got == nil || got.String() != tt.want {
+					t.Errorf("proxy = %v, %v; want %s", got, err, tt.want)
+				}
+				return
+			}
+			if fmt.Sprint(got) != fmt.Sprint(fromEnv) || (err == nil) != (envErr == nil) {
+				t.Errorf("proxy = %v, %v; want the environment's %v, %v", got, err, fromEnv, envErr)
+			}
+		})
+	}
+}
+
+// TestOutboundProxyRoutesRequests sends a request through a transport set up
+// like the finance-go and scrape transports and checks it reaches the proxy
+func TestOutboundProxyRoutesRequests(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String() // A proxy sees the absolute URL
+	}))
+	defer proxy.Close()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = outboundProxy(Config{ProxyURL: proxy.URL})
+	resp, err := (&http.Client{Transport: transport}).Get("http://en.wikipedia.invalid/wiki/List")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://en.wikipedia.invalid/wiki/List" {
+		t.Errorf("proxy saw %q", proxied)
+	}
+}
+
+func TestNewCollectorTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(path, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		url     string
+		wantErr bool
+	}{
+		{name: "serves file URLs", ctx: context.Background(), url: "file://" + path},
+		{name: "serves HTTP", ctx: context.Background(), url: server.URL},
+		{name: "bound to the context", ctx: cancelled, url: server.URL, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := newCollector(tt.ctx).Visit(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("Visit error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
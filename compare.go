@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// loadSnapshot reads a previous run's results from a /api/results JSON dump
+// or a CSV written by writeResultsToCSV, chosen by file extension
+func loadSnapshot(path string) ([]Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer f.Close()
+
+	var results []Result
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&results); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	case ".csv":
+		if results, err = readSnapshotCSV(f); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported snapshot format (expected .json or .csv)", path)
+	}
+	return results, nil
+}
+
+// readSnapshotCSV parses the ticker section of a results CSV, stopping at the
+// blank separator before the sector summary
+func readSnapshotCSV(r io.Reader) ([]Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[h] = i
+	}
+	for _, want := range []string{"Ticker", "Sector", "Return"} {
+		if _, ok := cols[want]; !ok {
+			return nil, fmt.Errorf("missing %q column in header", want)
+		}
+	}
+
+	var results []Result
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(record) == 1 && record[0] == "" {
+			break // Sector summary follows
+		}
+		if len(record) <= cols["Return"] {
+			return nil, fmt.Errorf("line %d: too few columns", line)
+		}
+
+		ret, err := strconv.ParseFloat(record[cols["Return"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid return %q (snapshots must use the default locale)", line, record[cols["Return"]])
+		}
+		results = append(results, Result{
+			Ticker: record[cols["Ticker"]],
+			Sector: record[cols["Sector"]],
+			Return: ret,
+		})
+	}
+	return results, nil
+}
+
+// ReturnDelta is a ticker's change in return between a snapshot and the current run
+type ReturnDelta struct {
+	Ticker   string  `json:"ticker"`
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"` // Current - Previous
+}
+
+// SnapshotDiff summarizes how the current results differ from a snapshot
+type SnapshotDiff struct {
+	Changes []ReturnDelta `json:"changes"` // Tickers in both, largest absolute delta first
+	Added   []string      `json:"added"`   // Tickers only in the current results
+	Removed []string      `json:"removed"` // Tickers only in the snapshot
+}
+
+// diffResults compares current results against a previous snapshot
+func diffResults(previous, current []Result) SnapshotDiff {
+	prev := make(map[string]Result, len(previous))
+	for _, r := range previous {
+		prev[r.Ticker] = r
+	}
+
+	diff := SnapshotDiff{Changes: []ReturnDelta{}, Added: []string{}, Removed: []string{}}
+	seen := make(map[string]bool, len(current))
+	for _, r := range current {
+		seen[r.Ticker] = true
+		p, ok := prev[r.Ticker]
+		if !ok {
+			diff.Added = append(diff.Added, r.Ticker)
+			continue
+		}
+		diff.Changes = append(diff.Changes, ReturnDelta{
+			Ticker:   r.Ticker,
+			Previous: p.Return,
+			Current:  r.Return,
+			Delta:    r.Return - p.Return,
+		})
+	}
+	for _, r := range previous {
+		if !seen[r.Ticker] {
+			diff.Removed = append(diff.Removed, r.Ticker)
+		}
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		return math.Abs(diff.Changes[i].Delta) > math.Abs(diff.Changes[j].Delta)
+	})
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// SectorDelta is a sector's change in average return between two runs.
+// Previous or Current is null when the sector is missing from that run.
+type SectorDelta struct {
+	Sector   string   `json:"sector"`
+	Previous *float64 `json:"previous"`
+	Current  *float64 `json:"current"`
+	Delta    *float64 `json:"delta"`
+}
+
+// diffSectors compares sector averages, largest absolute delta first and
+// sectors present in only one run last
+func diffSectors(previous, current []SectorReturn) []SectorDelta {
+	prev := make(map[string]float64, len(previous))
+	for _, sr := range previous {
+		prev[sr.Sector] = sr.AvgReturn
+	}
+
+	deltas := []SectorDelta{}
+	seen := make(map[string]bool, len(current))
+	for _, sr := range current {
+		seen[sr.Sector] = true
+		cur := sr.AvgReturn
+		d := SectorDelta{Sector: sr.Sector, Current: &cur}
+		if p, ok := prev[sr.Sector]; ok {
+			delta := cur - p
+			d.Previous, d.Delta = &p, &delta
+		}
+		deltas = append(deltas, d)
+	}
+	for _, sr := range previous {
+		if !seen[sr.Sector] {
+			p := sr.AvgReturn
+			deltas = append(deltas, SectorDelta{Sector: sr.Sector, Previous: &p})
+		}
+	}
+
+	sort.SliceStable(deltas, func(i, j int) bool {
+		a, b := deltas[i].Delta, deltas[j].Delta
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return math.Abs(*a) > math.Abs(*b)
+	})
+	return deltas
+}
+
+// logSnapshotDiff logs the added and removed tickers and the n largest return changes
+func logSnapshotDiff(diff SnapshotDiff, n int) {
+	log.Printf("\n🔀 Changes since snapshot: %d compared, %d added, %d removed",
+		len(diff.Changes), len(diff.Added), len(diff.Removed))
+	if len(diff.Added) > 0 {
+		log.Printf("➕ Added: %s", strings.Join(diff.Added, ", "))
+	}
+	if len(diff.Removed) > 0 {
+		log.Printf("➖ Removed: %s", strings.Join(diff.Removed, ", "))
+	}
+	if n > len(diff.Changes) {
+		n = len(diff.Changes)
+	}
+	if n <= 0 {
+		return
+	}
+	log.Printf("\n📊 Largest %d return changes:", n)
+	for _, c := range diff.Changes[:n] {
+		log.Printf("%-8s %9s -> %9s (%s pts)", c.Ticker,
+			formatNumber("%.2f%%", c.Previous*100),
+			formatNumber("%.2f%%", c.Current*100),
+			formatNumber("%+.2f", c.Delta*100))
+	}
+}
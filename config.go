@@ -0,0 +1,293 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Resolve OMAHA_TIMEZONE on hosts without a zoneinfo database
+)
+
+// Config holds settings loaded from the environment at startup
+type Config struct {
+	Exclude []string // Tickers or sectors that are never fetched
+
+	SymbolSuffixes map[string]string // Suffix rewrites from scraped symbols to Yahoo's form
+	ShareClasses   map[string]string // Extra share classes consolidated into one class; nil disables
+	Locale         string            // BCP 47 tag for human-facing number formatting (e.g. "de-DE")
+
+	Location *time.Location // Time zone of window boundaries and trading days
+
+	Russell1000CSV string // Path to the Russell 1000 constituents CSV (symbol, sector, name)
+
+	SP500Page string // S&P 500 constituents page: a Wikipedia mirror URL or a saved HTML snapshot
+
+	ReturnUnits string // Default units for CSV/JSON returns: percent or bps
+	CSVSort     string // CSV ticker row order: return (default) or ticker
+
+	FetchOrder string // Default order tickers are fetched in: scrape, alphabetical or sector
+
+	CSVPerSector bool // Also write one CSV per sector next to the combined CSV
+	XLSX         bool // Also write the results as an Excel workbook next to the CSV
+	CSVPivot     bool // Also write a sector-by-metric pivot CSV next to the CSV
+
+	Benchmark       string // Ticker fetched as each run's benchmark (e.g. "SPY"); empty disables it
+	CSVBenchmarkRow bool   // Write the benchmark's return in its own CSV section
+
+	RiskFreeRate string // Annualized risk-free rate ("0.05") or "irx"; empty disables excess returns
+
+	MaxFailureRate float64 // Fraction of failed tickers above which a run counts as failed
+	SummaryCount   int     // Number of top and bottom tickers logged at the end of a run
+	TickerLimit    int     // Fetch only the first N tickers of each run (0 fetches all)
+	MinAvgVolume   float64 // Drop tickers whose average daily volume is below this (0 disables)
+	MinPrice       float64 // Drop tickers whose last close is below this (0 disables)
+	MaxAbsReturn   float64 // Returns beyond ±this are held for review as likely data errors (0 disables)
+
+	MaxAnnualizedReturn float64 // Cap on |AnnualizedReturn| (0 disables)
+	SectorSummaryCount  int     // Number of top and bottom sectors logged at the end of a run
+
+	ChangeTolerance float64 // Return difference below which ?changedSince= treats a ticker as unchanged
+
+	TrimFraction float64 // Fraction of returns dropped from each end for trimmed means
+
+	Weighting  string // Default weighting of sector and index averages: equal or custom
+	WeightsCSV string // Path to the custom weights CSV (symbol, weight)
+
+	FetchTimeout   time.Duration // HTTP timeout for each finance-go request
+	ProxyURL       string        // Proxy for Wikipedia and Yahoo requests; empty uses HTTP_PROXY/HTTPS_PROXY
+	TickerCacheTTL time.Duration // How long a scraped ticker list is reused
+	ScrapeWorkers  int           // Index pages fetched concurrently for a combined universe
+	ScrapeTimeout  time.Duration // Limit on scraping a run's ticker universe, including every index page
+
+	FetchRetries int // Retries of a ticker's transient fetch failures
+	RetryBudget  int // Retries allowed across a whole run (0 disables retries)
+
+	// Second pass over the main pass's transient failures
+	RetryPassWorkers  int // Concurrent fetches in the retry pass
+	RetryPassAttempts int // Fetches per ticker in the retry pass (0 disables it)
+
+	// Re-fetches of tickers that came back with no data, which Yahoo sometimes
+	// returns transiently (e.g. for recently listed tickers)
+	NoDataRetries    int           // Re-fetches per ticker before no data is final (0 disables them)
+	NoDataRetryDelay time.Duration // Wait before each re-fetch
+
+	// Connection pooling for finance-go requests
+	MaxIdleConnsPerHost int           // Idle connections kept per Yahoo host; at least the worker count
+	IdleConnTimeout     time.Duration // How long an idle connection is kept for reuse
+	TLSHandshakeTimeout time.Duration // Limit on each TLS handshake
+
+	FetchPaddingDays int    // Extra days fetched before the window start
+	Baseline         string // Default baseline strategy: first-in-window, prior-close or nearest
+
+	VolatilityLookbackDays int // Trailing sessions Volatility covers when more than the window (0 uses the window)
+
+	ForwardFill bool // Count trading days with no bar as flat sessions in Volatility
+
+	Metrics string // Default optional metrics, comma-separated; empty computes all, "none" none
+
+	TargetCurrency string // ISO 4217 currency returns are also converted into (e.g. "EUR"); empty disables it
+
+	UnknownSector string // Handling of Unknown-sector tickers: keep, drop or backfill
+
+	WebhookURL string // Optional URL that receives a JSON summary after each refresh
+	RPCAddr    string // Listen address for the JSON-RPC server; "off" disables it. It has no authentication, so it defaults to loopback
+	RunHistory int    // Completed runs kept in memory for /api/diff
+
+	ResultsCacheSize int // Runs cached by index and window for /api/results
+
+	ResetToken string // Bearer token /api/reset requires; empty leaves it open
+
+	MaxResponseResults int // Cap on the entries any /api/* list returns (0 disables)
+
+	// S3-compatible bucket that receives each run's artifacts; empty S3Bucket disables exports
+	S3Endpoint  string // Host[:port], e.g. "s3.amazonaws.com"
+	S3Region    string
+	S3Bucket    string
+	S3Prefix    string // Key prefix for all artifacts
+	S3AccessKey string // Falls back to AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY when empty
+	S3SecretKey string
+	S3Insecure  bool // Use plain HTTP, for local S3-compatible servers
+
+	StaleDays    int // Days a ticker's last bar may lag before it is flagged Stale
+	StaleMaxDays int // Days of lag after which a ticker is treated as an error (0 disables)
+}
+
+// cfg is the active configuration, loaded once at startup
+var cfg = loadConfig()
+
+// loadConfig reads the configuration from OMAHA_* environment variables
+func loadConfig() Config {
+	return Config{
+		Exclude: splitList(os.Getenv("OMAHA_EXCLUDE")),
+
+		SymbolSuffixes: envSymbolMap("OMAHA_SYMBOL_SUFFIXES", defaultSymbolSuffixes),
+		ShareClasses:   envShareClasses("OMAHA_SHARE_CLASSES"),
+		Locale:         os.Getenv("OMAHA_LOCALE"),
+
+		Location: envLocation("OMAHA_TIMEZONE", "America/New_York"),
+
+		Russell1000CSV: os.Getenv("OMAHA_RUSSELL1000_CSV"),
+
+		SP500Page: os.Getenv("OMAHA_SP500_PAGE"),
+
+		ReturnUnits: os.Getenv("OMAHA_RETURN_UNITS"),
+		CSVSort:     os.Getenv("OMAHA_CSV_SORT"),
+
+		FetchOrder: os.Getenv("OMAHA_FETCH_ORDER"),
+
+		CSVPerSector: os.Getenv("OMAHA_CSV_PER_SECTOR") == "true",
+		XLSX:         os.Getenv("OMAHA_XLSX") == "true",
+		CSVPivot:     os.Getenv("OMAHA_CSV_PIVOT") == "true",
+
+		Benchmark:       strings.ToUpper(strings.TrimSpace(os.Getenv("OMAHA_BENCHMARK"))),
+		CSVBenchmarkRow: os.Getenv("OMAHA_CSV_BENCHMARK_ROW") != "false",
+
+		RiskFreeRate: os.Getenv("OMAHA_RISK_FREE_RATE"),
+
+		MaxFailureRate: envFloat("OMAHA_MAX_FAILURE_RATE", 0.2),
+		SummaryCount:   envInt("OMAHA_SUMMARY_COUNT", 5),
+		TickerLimit:    envInt("OMAHA_TICKER_LIMIT", 0),
+		MinAvgVolume:   envFloat("OMAHA_MIN_AVG_VOLUME", 0),
+		MinPrice:       envFloat("OMAHA_MIN_PRICE", 0),
+		MaxAbsReturn:   envFloat("OMAHA_MAX_ABS_RETURN", 5),
+
+		MaxAnnualizedReturn: envFloat("OMAHA_MAX_ANNUALIZED_RETURN", 10),
+		SectorSummaryCount:  envInt("OMAHA_SECTOR_SUMMARY_COUNT", 5),
+
+		ChangeTolerance: envFloat("OMAHA_CHANGE_TOLERANCE", 0.0001),
+
+		TrimFraction: envFloat("OMAHA_TRIM_FRACTION", 0.05),
+
+		Weighting:  os.Getenv("OMAHA_WEIGHTING"),
+		WeightsCSV: os.Getenv("OMAHA_WEIGHTS_CSV"),
+
+		FetchTimeout:   envDuration("OMAHA_FETCH_TIMEOUT", 30*time.Second),
+		ProxyURL:       os.Getenv("OMAHA_PROXY_URL"),
+		TickerCacheTTL: envDuration("OMAHA_TICKER_CACHE_TTL", 24*time.Hour),
+		ScrapeWorkers:  envInt("OMAHA_SCRAPE_WORKERS", 2),
+		ScrapeTimeout:  envDuration("OMAHA_SCRAPE_TIMEOUT", time.Minute),
+
+		FetchRetries: envInt("OMAHA_FETCH_RETRIES", 2),
+		RetryBudget:  envInt("OMAHA_RETRY_BUDGET", 50),
+
+		RetryPassWorkers:  envInt("OMAHA_RETRY_PASS_WORKERS", 2),
+		RetryPassAttempts: envInt("OMAHA_RETRY_PASS_ATTEMPTS", 1),
+
+		NoDataRetries:    envInt("OMAHA_NO_DATA_RETRIES", 1),
+		NoDataRetryDelay: envDuration("OMAHA_NO_DATA_RETRY_DELAY", 2*time.Second),
+
+		MaxIdleConnsPerHost: envInt("OMAHA_MAX_IDLE_CONNS_PER_HOST", maxWorkers),
+		IdleConnTimeout:     envDuration("OMAHA_IDLE_CONN_TIMEOUT", 90*time.Second),
+		TLSHandshakeTimeout: envDuration("OMAHA_TLS_HANDSHAKE_TIMEOUT", 10*time.Second),
+
+		FetchPaddingDays: envInt("OMAHA_FETCH_PADDING_DAYS", 0),
+		Baseline:         os.Getenv("OMAHA_BASELINE"),
+
+		VolatilityLookbackDays: envInt("OMAHA_VOLATILITY_LOOKBACK_DAYS", 0),
+
+		ForwardFill: os.Getenv("OMAHA_FORWARD_FILL") == "true",
+
+		Metrics: os.Getenv("OMAHA_METRICS"),
+
+		TargetCurrency: strings.ToUpper(strings.TrimSpace(os.Getenv("OMAHA_TARGET_CURRENCY"))),
+
+		UnknownSector: os.Getenv("OMAHA_UNKNOWN_SECTOR"),
+
+		WebhookURL: os.Getenv("OMAHA_WEBHOOK_URL"),
+		RPCAddr:    envString("OMAHA_RPC_ADDR", "127.0.0.1:8081"),
+		RunHistory: envInt("OMAHA_RUN_HISTORY", 10),
+
+		ResultsCacheSize: envInt("OMAHA_RESULTS_CACHE_SIZE", 8),
+
+		ResetToken: os.Getenv("OMAHA_RESET_TOKEN"),
+
+		MaxResponseResults: envInt("OMAHA_MAX_RESPONSE_RESULTS", 5000),
+
+		S3Endpoint:  envString("OMAHA_S3_ENDPOINT", "s3.amazonaws.com"),
+		S3Region:    os.Getenv("OMAHA_S3_REGION"),
+		S3Bucket:    os.Getenv("OMAHA_S3_BUCKET"),
+		S3Prefix:    os.Getenv("OMAHA_S3_PREFIX"),
+		S3AccessKey: os.Getenv("OMAHA_S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("OMAHA_S3_SECRET_KEY"),
+		S3Insecure:  os.Getenv("OMAHA_S3_INSECURE") == "true",
+
+		StaleDays:    envInt("OMAHA_STALE_DAYS", 3),
+		StaleMaxDays: envInt("OMAHA_STALE_MAX_DAYS", 0),
+	}
+}
+
+// envString reads a string environment variable, falling back to def when unset
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envLocation reads an IANA time zone name (e.g. "America/New_York"), falling back to def when unset or invalid
+func envLocation(key, def string) *time.Location {
+	name := envString(key, def)
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, name, def)
+		loc, err = time.LoadLocation(def)
+		if err != nil {
+			return time.UTC
+		}
+	}
+	return loc
+}
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return n
+}
+
+// envFloat reads a float environment variable, falling back to def when unset or invalid
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return f
+}
+
+// splitList splits a comma-separated value into trimmed, non-empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// envDuration reads a duration environment variable (e.g. "30s"), falling back to def when unset or invalid
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, v, def)
+		return def
+	}
+	return d
+}
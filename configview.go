@@ -0,0 +1,225 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// redacted replaces secret settings in /api/config
+const redacted = "[redacted]"
+
+// configResponse is the JSON body returned by /api/config: the effective
+// settings, with derived values resolved and secrets redacted
+type configResponse struct {
+	Workers            int    `json:"workers"` // Fetch worker pool size after clamping to maxWorkers
+	ScrapeWorkers      int    `json:"scrape_workers"`
+	ScrapeTimeout      string `json:"scrape_timeout"`
+	FetchTimeout       string `json:"fetch_timeout"`
+	FetchRetries       int    `json:"fetch_retries"`
+	RetryBudget        int    `json:"retry_budget"`
+	RetryPassWorkers   int    `json:"retry_pass_workers"`
+	RetryPassAttempts  int    `json:"retry_pass_attempts"`
+	NoDataRetries      int    `json:"no_data_retries"`
+	NoDataRetryDelay   string `json:"no_data_retry_delay"`
+	ProxyURL           string `json:"proxy_url"` // Password redacted
+	TickerCacheTTL     string `json:"ticker_cache_ttl"`
+	OutputFile         string `json:"output_file"`
+	CheckpointInterval int    `json:"checkpoint_interval"`
+	Timezone           string `json:"timezone"`
+	Locale             string `json:"locale"`
+
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host"`
+	IdleConnTimeout     string `json:"idle_conn_timeout"`
+	TLSHandshakeTimeout string `json:"tls_handshake_timeout"`
+
+	Exclude        []string          `json:"exclude"`
+	SymbolSuffixes map[string]string `json:"symbol_suffixes"`
+	ShareClasses   map[string]string `json:"share_classes"` // null when consolidation is off
+	Russell1000CSV string            `json:"russell1000_csv"`
+	SP500Page      string            `json:"sp500_page"`
+	Weighting      string            `json:"weighting"`
+	WeightsCSV     string            `json:"weights_csv"`
+	UnknownSector  string            `json:"unknown_sector"`
+	TickerLimit    int               `json:"ticker_limit"`
+
+	ReturnUnits      string `json:"return_units"`
+	CSVSort          string `json:"csv_sort"`
+	CSVPerSector     bool   `json:"csv_per_sector"`
+	FetchOrder       string `json:"fetch_order"`
+	Benchmark        string `json:"benchmark"`
+	CSVBenchmarkRow  bool   `json:"csv_benchmark_row"`
+	XLSX             bool   `json:"xlsx"`
+	CSVPivot         bool   `json:"csv_pivot"`
+	Baseline         string `json:"baseline"`
+	FetchPaddingDays int    `json:"fetch_padding_days"`
+	RiskFreeRate     string `json:"risk_free_rate"`
+
+	VolatilityLookbackDays int  `json:"volatility_lookback_days"`
+	ForwardFill            bool `json:"forward_fill"`
+
+	Metrics        []string `json:"metrics"`
+	TargetCurrency string   `json:"target_currency"`
+
+	MaxFailureRate      float64 `json:"max_failure_rate"`
+	MinAvgVolume        float64 `json:"min_avg_volume"`
+	MinPrice            float64 `json:"min_price"`
+	MaxAbsReturn        float64 `json:"max_abs_return"`
+	MaxAnnualizedReturn float64 `json:"max_annualized_return"`
+	ChangeTolerance     float64 `json:"change_tolerance"`
+	TrimFraction        float64 `json:"trim_fraction"`
+	StaleDays           int     `json:"stale_days"`
+	StaleMaxDays        int     `json:"stale_max_days"`
+
+	SummaryCount       int `json:"summary_count"`
+	SectorSummaryCount int `json:"sector_summary_count"`
+	RunHistory         int `json:"run_history"`
+	ResultsCacheSize   int `json:"results_cache_size"`
+	MaxResponseResults int `json:"max_response_results"`
+
+	WebhookURL string `json:"webhook_url"` // Redacted when set: the URL carries the token
+	RPCAddr    string `json:"rpc_addr"`
+
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3Prefix    string `json:"s3_prefix"`
+	S3AccessKey string `json:"s3_access_key"` // Redacted when set, like the secret key, which is omitted
+	S3Insecure  bool   `json:"s3_insecure"`
+}
+
+// newConfigResponse describes c as served by /api/config
+func newConfigResponse(c Config) configResponse {
+	webhook := ""
+	if c.WebhookURL != "" {
+		webhook = redacted
+	}
+	accessKey := ""
+	if c.S3AccessKey != "" {
+		accessKey = redacted
+	}
+	return configResponse{
+		Workers:            fetchWorkers(),
+		ScrapeWorkers:      c.ScrapeWorkers,
+		ScrapeTimeout:      c.ScrapeTimeout.String(),
+		FetchTimeout:       c.FetchTimeout.String(),
+		FetchRetries:       c.FetchRetries,
+		RetryBudget:        c.RetryBudget,
+		RetryPassWorkers:   c.RetryPassWorkers,
+		RetryPassAttempts:  c.RetryPassAttempts,
+		NoDataRetries:      c.NoDataRetries,
+		NoDataRetryDelay:   c.NoDataRetryDelay.String(),
+		ProxyURL:           redactURL(c.ProxyURL),
+		TickerCacheTTL:     c.TickerCacheTTL.String(),
+		OutputFile:         outputFile,
+		CheckpointInterval: checkpointInterval,
+		Timezone:           c.Location.String(),
+		Locale:             c.Locale,
+
+		MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
+		IdleConnTimeout:     c.IdleConnTimeout.String(),
+		TLSHandshakeTimeout: c.TLSHandshakeTimeout.String(),
+
+		Exclude:        nonNil(c.Exclude),
+		SymbolSuffixes: c.SymbolSuffixes,
+		ShareClasses:   c.ShareClasses,
+		Russell1000CSV: c.Russell1000CSV,
+		SP500Page:      orDefault(c.SP500Page, wikipediaPages[indexSP500]),
+		Weighting:      orDefault(c.Weighting, weightingEqual),
+		WeightsCSV:     c.WeightsCSV,
+		UnknownSector:  orDefault(c.UnknownSector, unknownKeep),
+		TickerLimit:    c.TickerLimit,
+
+		ReturnUnits:      normalizeUnits(c.ReturnUnits),
+		CSVSort:          orDefault(c.CSVSort, csvSortReturn),
+		CSVPerSector:     c.CSVPerSector,
+		FetchOrder:       orDefault(c.FetchOrder, fetchOrderScrape),
+		Benchmark:        c.Benchmark,
+		CSVBenchmarkRow:  c.CSVBenchmarkRow,
+		XLSX:             c.XLSX,
+		CSVPivot:         c.CSVPivot,
+		Baseline:         orDefault(c.Baseline, baselineFirstInWindow),
+		FetchPaddingDays: c.FetchPaddingDays,
+		RiskFreeRate:     c.RiskFreeRate,
+
+		VolatilityLookbackDays: c.VolatilityLookbackDays,
+		ForwardFill:            c.ForwardFill,
+
+		Metrics:        configMetrics(c.Metrics),
+		TargetCurrency: c.TargetCurrency,
+
+		MaxFailureRate:      c.MaxFailureRate,
+		MinAvgVolume:        c.MinAvgVolume,
+		MinPrice:            c.MinPrice,
+		MaxAbsReturn:        c.MaxAbsReturn,
+		MaxAnnualizedReturn: c.MaxAnnualizedReturn,
+		ChangeTolerance:     c.ChangeTolerance,
+		TrimFraction:        c.TrimFraction,
+		StaleDays:           c.StaleDays,
+		StaleMaxDays:        c.StaleMaxDays,
+
+		SummaryCount:       c.SummaryCount,
+		SectorSummaryCount: c.SectorSummaryCount,
+		RunHistory:         c.RunHistory,
+		ResultsCacheSize:   c.ResultsCacheSize,
+		MaxResponseResults: c.MaxResponseResults,
+
+		WebhookURL: webhook,
+		RPCAddr:    c.RPCAddr,
+
+		S3Endpoint:  c.S3Endpoint,
+		S3Region:    c.S3Region,
+		S3Bucket:    c.S3Bucket,
+		S3Prefix:    c.S3Prefix,
+		S3AccessKey: accessKey,
+		S3Insecure:  c.S3Insecure,
+	}
+}
+
+// redactURL masks the password in a URL, or the whole value if it doesn't
+// parse as scheme://host (e.g. "user:pass@host" without a scheme)
+func redactURL(s string) string {
+	if s == "" {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Opaque != "" {
+		return redacted
+	}
+	return u.Redacted()
+}
+
+// orDefault returns s, or def when s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// nonNil returns s, or an empty slice so it encodes as [] rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return slices.Clone(s)
+}
+
+// configMetrics lists the metrics OMAHA_METRICS selects, all of them when it is invalid
+func configMetrics(list string) []string {
+	m, err := parseMetrics(list)
+	if err != nil {
+		m = allMetrics
+	}
+	return m.names()
+}
+
+// handleConfig returns the effective configuration
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, "read-only endpoint")
+		return
+	}
+	writeJSON(w, http.StatusOK, newConfigResponse(cfg))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// Event topics published on the server's event bus
+const (
+	topicResultsUpdated = "results-updated" // Published after UpdateResults swaps in new results
+	topicTickerResult   = "ticker-result"   // Published for each ticker as a refresh completes it
+)
+
+// Event is a message delivered to event bus subscribers
+type Event struct {
+	Topic   string
+	Summary RunSummary
+	Results []Result // For topicTickerResult, the single completed ticker
+}
+
+// EventBus is a small in-memory pub/sub. Publishing never blocks: events are
+// dropped for subscribers whose buffer is full.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Event]struct{}
+}
+
+// NewEventBus creates an empty event bus
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving events for topic and a function that
+// unsubscribes and closes it. buffer sets how many events may queue up unread.
+func (b *EventBus) Subscribe(topic string, buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan Event]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[topic], ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers e to every subscriber of its topic without blocking
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs[e.Topic] {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("Warning: dropped %s event for a slow subscriber", e.Topic)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+)
+
+// Exporter uploads a run's artifacts to external storage
+type Exporter interface {
+	// Put stores body under key
+	Put(ctx context.Context, key, contentType string, body []byte) error
+}
+
+// exportTimeout bounds the upload of all of a run's artifacts
+const exportTimeout = 2 * time.Minute
+
+// exporter is the configured artifact exporter, or nil when exports are off
+var exporter = newExporter(cfg)
+
+// newExporter returns the exporter configured in c, or nil when none is.
+// Misconfiguration is logged and disables exports rather than failing runs.
+func newExporter(c Config) Exporter {
+	if c.S3Bucket == "" {
+		return nil
+	}
+	e, err := newS3Exporter(c)
+	if err != nil {
+		log.Printf("Warning: S3 export disabled: %v", err)
+		return nil
+	}
+	return e
+}
+
+// exportKeyPrefix returns the key prefix for a run's artifacts: the configured
+// prefix, then the window, then the run ID (which starts with its timestamp)
+func exportKeyPrefix(prefix string, summary RunSummary) string {
+	period := summary.Start.Format("2006-01-02") + "_" + summary.End.Format("2006-01-02")
+	return path.Join(prefix, period, summary.RunID)
+}
+
+// exportRun uploads the run's CSV (when written), results and summary,
+// recording the uploaded keys or the first failure in the summary
+func exportRun(e Exporter, summary *RunSummary, results []Result, csvPath string) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	type artifact struct {
+		name, contentType string
+		body              func() ([]byte, error)
+	}
+	artifacts := []artifact{
+		{"results.json", "application/json", func() ([]byte, error) { return json.Marshal(results) }},
+		{"summary.json", "application/json", func() ([]byte, error) { return json.Marshal(summary) }},
+	}
+	if csvPath != "" {
+		artifacts = append([]artifact{{"results.csv", "text/csv", func() ([]byte, error) { return os.ReadFile(csvPath) }}}, artifacts...)
+	}
+
+	prefix := exportKeyPrefix(cfg.S3Prefix, *summary)
+	for _, a := range artifacts {
+		key := path.Join(prefix, a.name)
+		body, err := a.body()
+		if err == nil {
+			err = e.Put(ctx, key, a.contentType, body)
+		}
+		if err != nil {
+			summary.ExportError = fmt.Sprintf("%s: %v", key, err)
+			log.Printf("Warning: export failed: %s", summary.ExportError)
+			return
+		}
+		summary.Exported = append(summary.Exported, key)
+	}
+	log.Printf("☁️  Exported %d artifacts to %s\n", len(summary.Exported), prefix)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/form"
+)
+
+// fakeBar is one daily bar served by fakeYahoo
+type fakeBar struct {
+	date      string  // Session date, 2006-01-02; the bar opens at 9:30 in cfg.Location
+	close     float64 // Close; high and low default to it
+	high, low float64
+	volume    int
+}
+
+// Special errors to queue for a symbol: errEmptyChart serves one chart with
+// no bars, and errPanic panics inside the fetch
+var (
+	errEmptyChart = errors.New("empty chart")
+	errPanic      = errors.New("panic")
+)
+
+// fakeYahoo is a finance.Backend serving canned charts and quotes, so fetches
+// run without the network. Queued errors are returned one per call before the
+// symbol's chart is served.
+type fakeYahoo struct {
+	mu       sync.Mutex
+	charts   map[string][]fakeBar
+	currency map[string]string // Chart currency per symbol; USD when unset
+	quotes   map[string]finance.Quote
+	queued   map[string][]error
+	calls    map[string]int // Chart requests per symbol
+	batches  []int          // Symbols per quote request, in order
+	delay    time.Duration  // Wait before serving each chart
+}
+
+func newFakeYahoo() *fakeYahoo {
+	return &fakeYahoo{
+		charts:   make(map[string][]fakeBar),
+		currency: make(map[string]string),
+		quotes:   make(map[string]finance.Quote),
+		queued:   make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// install makes f the finance-go backend until the test ends
+func (f *fakeYahoo) install(t *testing.T) {
+	t.Helper()
+	prev := finance.GetBackend(finance.YFinBackend)
+	finance.SetBackend(finance.YFinBackend, f)
+	t.Cleanup(func() { finance.SetBackend(finance.YFinBackend, prev) })
+}
+
+// queue makes the next len(errs) chart requests for symbol fail with errs in order
+func (f *fakeYahoo) queue(symbol string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued[symbol] = append(f.queued[symbol], errs...)
+}
+
+// chartCalls returns the chart requests made for symbol
+func (f *fakeYahoo) chartCalls(symbol string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[symbol]
+}
+
+func (f *fakeYahoo) Call(path string, body *form.Values, _ *context.Context, v any) error {
+	if symbol, ok := strings.CutPrefix(path, "v8/finance/chart/"); ok {
+		if f.delay > 0 {
+			time.Sleep(f.delay)
+		}
+		return f.chart(symbol, body, v)
+	}
+	if path == finance.YQuotePath {
+		return f.quote(body, v)
+	}
+	return &finance.RemoteError{StatusCode: 404, Msg: "unknown path " + path}
+}
+
+func (f *fakeYahoo) chart(symbol string, body *form.Values, v any) error {
+	f.mu.Lock()
+	f.calls[symbol]++
+	var queued error
+	if q := f.queued[symbol]; len(q) > 0 {
+		queued, f.queued[symbol] = q[0], q[1:]
+	}
+	bars, known := f.charts[symbol]
+	currency := f.currency[symbol]
+	f.mu.Unlock()
+
+	if queued == errPanic {
+		panic("fake chart panic for " + symbol)
+	}
+	if queued != nil && queued != errEmptyChart {
+		return queued
+	}
+	if !known && queued == nil {
+		return &finance.RemoteError{StatusCode: 404, Msg: "no chart for " + symbol}
+	}
+	if currency == "" {
+		currency = "USD"
+	}
+
+	from, to := formInt(body, "period1"), formInt(body, "period2")
+	var stamps []int
+	var opens, highs, lows, closes []float64
+	var volumes []int
+	for _, b := range bars {
+		if queued == errEmptyChart {
+			break
+		}
+		day, err := time.ParseInLocation("2006-01-02", b.date, cfg.Location)
+		if err != nil {
+			return err
+		}
+		ts := int(day.Add(9*time.Hour + 30*time.Minute).Unix())
+		if ts < from || ts >= to {
+			continue
+		}
+		high, low := b.high, b.low
+		if high == 0 {
+			high = b.close
+		}
+		if low == 0 {
+			low = b.close
+		}
+		stamps = append(stamps, ts)
+		opens = append(opens, b.close)
+		highs = append(highs, high)
+		lows = append(lows, low)
+		closes = append(closes, b.close)
+		volumes = append(volumes, b.volume)
+	}
+
+	return roundTrip(map[string]any{"chart": map[string]any{"result": []any{map[string]any{
+		"meta":      map[string]any{"symbol": symbol, "currency": currency},
+		"timestamp": stamps,
+		"indicators": map[string]any{"quote": []any{map[string]any{
+			"open": opens, "high": highs, "low": lows, "close": closes, "volume": volumes,
+		}}},
+	}}}}, v)
+}
+
+func (f *fakeYahoo) quote(body *form.Values, v any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []finance.Quote
+	for _, symbols := range body.Get("symbols") {
+		f.batches = append(f.batches, len(strings.Split(symbols, ",")))
+		for _, s := range strings.Split(symbols, ",") {
+			if q, ok := f.quotes[s]; ok {
+				q.Symbol = s
+				result = append(result, q)
+			}
+		}
+	}
+	return roundTrip(map[string]any{"quoteResponse": map[string]any{"result": result}}, v)
+}
+
+// roundTrip decodes resp into v through JSON, as the real backend would
+func roundTrip(resp any, v any) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func formInt(body *form.Values, key string) int {
+	if vals := body.Get(key); len(vals) > 0 {
+		n, _ := strconv.Atoi(vals[0])
+		return n
+	}
+	return 0
+}
+
+// dailyBars returns one bar per trading day from start through end (both
+// 2006-01-02), with closes from price(i) for the i-th session
+func dailyBars(start, end string, price func(i int) float64) []fakeBar {
+	from, _ := time.Parse("2006-01-02", start)
+	to, _ := time.Parse("2006-01-02", end)
+	var bars []fakeBar
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if isTradingDay(d) {
+			bars = append(bars, fakeBar{date: d.Format("2006-01-02"), close: price(len(bars)), volume: 1000})
+		}
+	}
+	return bars
+}
+
+// withConfig applies change to cfg for the rest of the test
+func withConfig(t *testing.T, change func(c *Config)) {
+	t.Helper()
+	saved := cfg
+	change(&cfg)
+	t.Cleanup(func() { cfg = saved })
+}
+
+// newFakeRun installs a fakeYahoo and isolates the test's runs: outputs go
+// to a temp working directory, checkpoints to a temp TMPDIR, and retries
+// don't wait. Runs within one test share the checkpoint directory.
+func newFakeRun(t *testing.T) *fakeYahoo {
+	t.Helper()
+	f := newFakeYahoo()
+	f.install(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+	withConfig(t, func(c *Config) {
+		c.NoDataRetryDelay = 0
+		c.RetryPassAttempts = 0
+		c.FetchRetries = 0
+		c.Benchmark = ""
+		c.RiskFreeRate = ""
+		c.WebhookURL = ""
+		c.CSVPerSector, c.CSVPivot, c.XLSX = false, false, false
+	})
+	return f
+}
+
+// septemberStart is the start of the September 2025 window runFake runs over;
+// Labor Day falls on the 1st
+var septemberStart, septemberEnd = getMonthRange(2025, time.September, 1)
+
+// runFake runs getMTDResults for September 2025, as of mid-October, over
+// tickers, all in sector Tech
+func runFake(t *testing.T, tickers []string, opts RunOptions) ([]Result, RunSummary, error) {
+	t.Helper()
+	var u Universe
+	for _, ticker := range tickers {
+		u.add(ticker, "Tech", "")
+	}
+	opts.Source = func() (Universe, error) { return u, nil }
+	if opts.Now == nil {
+		opts.Now = func() time.Time { return time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC) }
+	}
+	return getMTDResults(2025, time.September, 1, opts)
+}
+
+// resultFor returns the result for ticker, failing the test if there is none
+func resultFor(t *testing.T, results []Result, ticker string) Result {
+	t.Helper()
+	for _, r := range results {
+		if r.Ticker == ticker {
+			return r
+		}
+	}
+	t.Fatalf("no result for %s in %d results", ticker, len(results))
+	return Result{}
+}
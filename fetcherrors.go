@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/piquette/finance-go"
+)
+
+// Typed per-ticker failures. getMTDReturn wraps recognized finance-go errors in
+// the fetch sentinels so callers can branch with errors.Is.
+var (
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrNoData         = errors.New("no data")
+	ErrBadData        = errors.New("bad data")
+
+	// Results rejected after a successful fetch
+	ErrInvalidReturn = errors.New("invalid return")
+	ErrStale         = errors.New("stale data")
+	ErrBelowMinPrice = errors.New("below minimum price")
+	ErrIlliquid      = errors.New("illiquid")
+)
+
+// errorCategories maps each typed failure to its category at /api/errors
+var errorCategories = []struct {
+	err      error
+	category string
+}{
+	{ErrSymbolNotFound, "symbol_not_found"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrNoData, "no_data"},
+	{ErrBadData, "bad_data"},
+	{ErrInvalidReturn, "invalid_return"},
+	{ErrStale, "stale"},
+	{ErrBelowMinPrice, "below_min_price"},
+	{ErrIlliquid, "illiquid"},
+}
+
+// categoryFetchError is the category of failures with no typed error
+const categoryFetchError = "fetch_error"
+
+// classifyFetchError maps a finance-go error to its typed sentinel, or nil if unrecognized
+func classifyFetchError(err error) error {
+	var remote *finance.RemoteError
+	if errors.As(err, &remote) {
+		switch remote.StatusCode {
+		case http.StatusNotFound:
+			return ErrSymbolNotFound
+		case http.StatusTooManyRequests:
+			return ErrRateLimited
+		}
+	}
+	var yerr *finance.YfinError
+	if errors.As(err, &yerr) {
+		switch strings.ToLower(yerr.Code) {
+		case "not found":
+			return ErrSymbolNotFound
+		case "too many requests":
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
+
+// errorCategory returns the /api/errors category of a per-ticker failure
+func errorCategory(err error) string {
+	for _, c := range errorCategories {
+		if errors.Is(err, c.err) {
+			return c.category
+		}
+	}
+	return categoryFetchError
+}
+
+// TickerError is a single ticker's failure from a run
+type TickerError struct {
+	Ticker   string `json:"ticker"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// newTickerError records err as ticker's failure
+func newTickerError(ticker string, err error) TickerError {
+	return TickerError{Ticker: ticker, Category: errorCategory(err), Message: err.Error()}
+}
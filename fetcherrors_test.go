@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	finance "github.com/piquette/finance-go"
+)
+
+func TestFetchErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		want     error // nil for an unrecognized error
+		category string
+	}{
+		{name: "remote 404", err: &finance.RemoteError{StatusCode: 404}, want: ErrSymbolNotFound, category: "symbol_not_found"},
+		{name: "remote 429", err: &finance.RemoteError{StatusCode: 429}, want: ErrRateLimited, category: "rate_limited"},
+		{name: "remote 500", err: &finance.RemoteError{StatusCode: 500}, category: categoryFetchError},
+		{name: "yahoo not found", err: &finance.YfinError{Code: "Not Found"}, want: ErrSymbolNotFound, category: "symbol_not_found"},
+		{name: "yahoo too many requests", err: &finance.YfinError{Code: "Too Many Requests"}, want: ErrRateLimited, category: "rate_limited"},
+		{name: "yahoo other code", err: &finance.YfinError{Code: "Bad Request"}, category: categoryFetchError},
+		{name: "plain error", err: errors.New("connection reset"), category: categoryFetchError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyFetchError(fmt.Errorf("wrapped: %w", tt.err)); got != tt.want {
+				t.Errorf("classifyFetchError = %v, want %v", got, tt.want)
+			}
+
+			// The same error served by the provider comes back from getMTDReturn typed
+			f := newFakeRun(t)
+			f.queue("AAA", tt.err)
+			_, err := getMTDReturn(context.Background(), "AAA", septemberStart, septemberEnd, FetchOptions{})
+			if err == nil {
+				t.Fatal("getMTDReturn succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("getMTDReturn error %v is not %v", err, tt.want)
+			}
+			if got := errorCategory(err); got != tt.category {
+				t.Errorf("category = %s, want %s", got, tt.category)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// numberPrinter formats human-facing numbers for the configured locale.
+// A nil printer keeps the plain fmt (US) formatting.
+var numberPrinter = newNumberPrinter(cfg.Locale)
+
+// newNumberPrinter returns a locale-aware printer, or nil for the default format
+func newNumberPrinter(locale string) *message.Printer {
+	if locale == "" {
+		return nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		log.Printf("Warning: unknown locale %q, using default number format: %v", locale, err)
+		return nil
+	}
+	return message.NewPrinter(tag)
+}
+
+// formatNumber formats numbers for CSV and log output using the configured locale
+func formatNumber(format string, a ...any) string {
+	if numberPrinter == nil {
+		return fmt.Sprintf(format, a...)
+	}
+	return numberPrinter.Sprintf(format, a...)
+}
+
+// Return units for human-facing output
+const (
+	unitsPercent = "percent" // Default: percentages with two decimals (e.g. "4.56%")
+	unitsBps     = "bps"     // Basis points, return * 10000 (e.g. "456")
+)
+
+// formatReturn formats a fractional return in the given units. NaN maps to an empty string.
+func formatReturn(v float64, units string) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	if units == unitsBps {
+		return formatNumber("%.0f", v*10000)
+	}
+	return formatNumber("%.2f%%", v*100)
+}
+
+// formatOptionalReturn formats a return that may not have been computed
+func formatOptionalReturn(v *float64, units string) string {
+	if v == nil {
+		return ""
+	}
+	return formatReturn(*v, units)
+}
+
+// returnColumn names a CSV column holding returns in the given units (e.g. "MTD_%" or "MTD_bps")
+func returnColumn(name, units string) string {
+	if units == unitsBps {
+		return name + "_bps"
+	}
+	return name + "_%"
+}
+
+// normalizeUnits maps a user-supplied units value to a known unit, defaulting to percent
+func normalizeUnits(units string) string {
+	if units == unitsBps {
+		return unitsBps
+	}
+	return unitsPercent
+}
+
+// bpsResult is the JSON view of a Result with returns in basis points.
+// The shadowing pointer fields encode NaN as null.
+type bpsResult struct {
+	Result
+	Return           *float64
+	ExcessReturn     *float64 `json:",omitempty"`
+	RelativeToSector *float64 `json:",omitempty"`
+	AnnualizedReturn *float64 `json:",omitempty"`
+	Volatility       *float64 `json:",omitempty"`
+	MaxDrawdown      *float64 `json:",omitempty"`
+	CurrencyReturn   *float64 `json:",omitempty"`
+	ReturnMTD        *float64 `json:"Return_MTD,omitempty"`
+	ReturnQTD        *float64 `json:"Return_QTD,omitempty"`
+	ReturnYTD        *float64 `json:"Return_YTD,omitempty"`
+}
+
+// toBpsResults converts results to their basis-point JSON view
+func toBpsResults(results []Result) []bpsResult {
+	bps := func(v float64) *float64 {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		b := math.Round(v * 10000)
+		return &b
+	}
+
+	out := make([]bpsResult, len(results))
+	for i, r := range results {
+		out[i] = bpsResult{Result: r, Return: bps(r.Return)}
+		if r.ExcessReturn != nil {
+			out[i].ExcessReturn = bps(*r.ExcessReturn)
+		}
+		if r.RelativeToSector != nil {
+			out[i].RelativeToSector = bps(*r.RelativeToSector)
+		}
+		if r.AnnualizedReturn != nil {
+			out[i].AnnualizedReturn = bps(*r.AnnualizedReturn)
+		}
+		if r.Volatility != nil {
+			out[i].Volatility = bps(*r.Volatility)
+		}
+		if r.MaxDrawdown != nil {
+			out[i].MaxDrawdown = bps(*r.MaxDrawdown)
+		}
+		if r.CurrencyReturn != nil {
+			out[i].CurrencyReturn = bps(*r.CurrencyReturn)
+		}
+		if r.ReturnMTD != nil {
+			out[i].ReturnMTD = bps(*r.ReturnMTD)
+		}
+		if r.ReturnQTD != nil {
+			out[i].ReturnQTD = bps(*r.ReturnQTD)
+		}
+		if r.ReturnYTD != nil {
+			out[i].ReturnYTD = bps(*r.ReturnYTD)
+		}
+	}
+	return out
+}
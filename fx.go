@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/chart"
+	"github.com/piquette/finance-go/datetime"
+	"github.com/shopspring/decimal"
+)
+
+// fxLookback is how far before the window start FX rates are fetched, so the
+// baseline always has a rate on or before it
+const fxLookback = 7
+
+// minorCurrencies maps Yahoo's minor-unit currency codes to their major
+// currency. Returns are price ratios, so the factor of 100 cancels out.
+var minorCurrencies = map[string]string{
+	"GBp": "GBP",
+	"GBX": "GBP",
+	"ZAc": "ZAR",
+	"ILA": "ILS",
+}
+
+// normalizeCurrency maps a quoted currency code to its ISO 4217 major currency
+func normalizeCurrency(code string) string {
+	if major, ok := minorCurrencies[code]; ok {
+		return major
+	}
+	return strings.ToUpper(code)
+}
+
+// validCurrency reports whether s looks like an ISO 4217 code (e.g. "EUR")
+func validCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// errFXUnavailable marks conversions skipped because the pair's rates
+// couldn't be fetched, which is logged once per pair rather than per ticker
+var errFXUnavailable = errors.New("exchange rates unavailable")
+
+// fxSeries is a pair's daily closing rates in date order
+type fxSeries struct {
+	times []time.Time       // Each bar's date, at midnight in cfg.Location
+	rates []decimal.Decimal // Units of the quote currency per unit of the base
+}
+
+// rateOn returns the last rate dated on or before t's date, reporting false
+// when the series starts after it
+func (s fxSeries) rateOn(t time.Time) (decimal.Decimal, bool) {
+	t = t.In(cfg.Location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, cfg.Location).AddDate(0, 0, 1)
+	rate, ok := decimal.Decimal{}, false
+	for i, bt := range s.times {
+		if !bt.Before(day) {
+			break
+		}
+		rate, ok = s.rates[i], true
+	}
+	return rate, ok
+}
+
+// FXSource fetches the daily rates of from in to between start and end
+type FXSource func(ctx context.Context, from, to string, start, end time.Time) (fxSeries, error)
+
+// yahooFX fetches a pair's daily closes from Yahoo's FROMTO=X chart
+func yahooFX(ctx context.Context, from, to string, start, end time.Time) (fxSeries, error) {
+	symbol := from + to + "=X"
+	params := &chart.Params{
+		Params:   finance.Params{Context: &ctx},
+		Symbol:   symbol,
+		Start:    datetime.FromUnix(int(start.Unix())),
+		End:      datetime.FromUnix(int(end.AddDate(0, 0, 1).Unix())), // end is inclusive
+		Interval: datetime.OneDay,
+	}
+	var s fxSeries
+	var stamps []int64
+	iter := chart.Get(params)
+	for iter.Next() {
+		bar := iter.Bar()
+		if !bar.Close.IsPositive() {
+			continue
+		}
+		stamps = append(stamps, int64(bar.Timestamp))
+		s.rates = append(s.rates, bar.Close)
+	}
+	if err := iter.Err(); err != nil {
+		return fxSeries{}, fmt.Errorf("failed to fetch %s: %w", symbol, err)
+	}
+
+	// FX bars open at midnight in the exchange's zone (e.g. London), which is
+	// the previous evening in New York, so date them by the chart's own offset
+	offset := 0
+	if meta, ok := iter.Iter.Meta().(finance.ChartMeta); ok {
+		offset = meta.Gmtoffset
+	}
+	for _, ts := range stamps {
+		d := time.Unix(ts+int64(offset), 0).UTC()
+		s.times = append(s.times, time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, cfg.Location))
+	}
+	if len(s.rates) == 0 {
+		return fxSeries{}, fmt.Errorf("%w for %s", ErrNoData, symbol)
+	}
+	return s, nil
+}
+
+// fxCache converts a run's results into one target currency, fetching each
+// currency pair's rates once for the whole run however many tickers share it
+type fxCache struct {
+	source     FXSource
+	target     string
+	start, end time.Time
+
+	mu      sync.Mutex
+	entries map[string]*fxEntry // By native currency
+}
+
+// fxEntry is one pair's rates, fetched by the first ticker that needs them
+type fxEntry struct {
+	once   sync.Once
+	series fxSeries
+	err    error
+}
+
+// newFXCache returns a cache converting into target over the window from start to end
+func newFXCache(source FXSource, target string, start, end time.Time) *fxCache {
+	return &fxCache{
+		source:  source,
+		target:  target,
+		start:   start.AddDate(0, 0, -fxLookback),
+		end:     end,
+		entries: make(map[string]*fxEntry),
+	}
+}
+
+// series returns the rates of native in the target currency
+func (c *fxCache) series(ctx context.Context, native string) (fxSeries, error) {
+	c.mu.Lock()
+	e, ok := c.entries[native]
+	if !ok {
+		e = &fxEntry{}
+		c.entries[native] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		e.series, e.err = c.source(ctx, native, c.target, c.start, c.end)
+		if e.err != nil {
+			log.Printf("Warning: no %s/%s rates, skipping conversions from %s: %v", native, c.target, native, e.err)
+		}
+	})
+	return e.series, e.err
+}
+
+// convert sets res.CurrencyReturn: the return with the baseline close
+// converted at the baseline date's rate and the last close at the last bar's
+func (c *fxCache) convert(ctx context.Context, res *MTDResult, returnType string) error {
+	native := normalizeCurrency(res.Currency)
+	if native == "" {
+		return fmt.Errorf("no currency reported")
+	}
+	if native == c.target {
+		converted := res.Return
+		res.CurrencyReturn = &converted
+		return nil
+	}
+
+	s, err := c.series(ctx, native)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFXUnavailable, err)
+	}
+	first, okFirst := s.rateOn(res.BaselineDate)
+	last, okLast := s.rateOn(res.LastBarTime)
+	if !okFirst || !okLast {
+		return fmt.Errorf("no %s/%s rate on or before %s", native, c.target, res.BaselineDate.Format("2006-01-02"))
+	}
+	converted := computeReturn(res.FirstClose.Mul(first), res.LastClose.Mul(last), returnType)
+	res.CurrencyReturn = &converted
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		code, want string
+		valid      bool
+	}{
+		{code: "USD", want: "USD", valid: true},
+		{code: "eur", want: "EUR", valid: true},
+		{code: "GBp", want: "GBP", valid: true},
+		{code: "GBX", want: "GBP", valid: true},
+		{code: "ZAc", want: "ZAR", valid: true},
+		{code: "ILA", want: "ILS", valid: true},
+		{code: "", want: ""},
+		{code: "EURO", want: "EURO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got := normalizeCurrency(tt.code)
+			if got != tt.want || validCurrency(got) != tt.valid {
+				t.Errorf("normalizeCurrency(%q) = %q (valid %t), want %q (valid %t)", tt.code, got, validCurrency(got), tt.want, tt.valid)
+			}
+		})
+	}
+}
+
+// fxRates builds a series from date: rate pairs in date order
+func fxRates(t *testing.T, pairs ...any) fxSeries {
+	var s fxSeries
+	for i := 0; i < len(pairs); i += 2 {
+		s.times = append(s.times, date(t, pairs[i].(string)))
+		s.rates = append(s.rates, decimal.NewFromFloat(pairs[i+1].(float64)))
+	}
+	return s
+}
+
+func TestRateOn(t *testing.T) {
+	s := fxRates(t, "2025-08-29", 1.10, "2025-09-02", 1.20, "2025-09-30", 1.30)
+	tests := []struct {
+		on     string
+		want   float64
+		wantOK bool
+	}{
+		{on: "2025-08-28"},
+		{on: "2025-08-29", want: 1.10, wantOK: true},
+		{on: "2025-09-01", want: 1.10, wantOK: true}, // Holiday: the last rate before it
+		{on: "2025-09-02", want: 1.20, wantOK: true},
+		{on: "2025-09-15", want: 1.20, wantOK: true},
+		{on: "2025-10-01", want: 1.30, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.on, func(t *testing.T) {
+			// Any time of day counts as its date
+			got, ok := s.rateOn(date(t, tt.on).Add(15*time.Hour + 59*time.Minute))
+			if ok != tt.wantOK || (ok && got.InexactFloat64() != tt.want) {
+				t.Errorf("rateOn(%s) = %v, %t; want %v, %t", tt.on, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// fakeFX serves fixed series per native currency, counting fetches
+type fakeFX struct {
+	series  map[string]fxSeries
+	fetches atomic.Int64
+}
+
+func (f *fakeFX) source(_ context.Context, from, to string, _, _ time.Time) (fxSeries, error) {
+	f.fetches.Add(1)
+	s, ok := f.series[from+to]
+	if !ok {
+		return fxSeries{}, ErrSymbolNotFound
+	}
+	return s, nil
+}
+
+func TestFXCacheConvert(t *testing.T) {
+	fx := &fakeFX{series: map[string]fxSeries{
+		"EURUSD": fxRates(t, "2025-08-29", 1.10, "2025-09-02", 1.00, "2025-09-30", 1.20),
+		"GBPUSD": fxRates(t, "2025-09-10", 1.30),
+	}}
+	tests := []struct {
+		name     string
+		currency string
+		baseline string
+		first    float64
+		last     float64
+		want     float64
+		wantErr  error // nil for no error; errAny for any other error
+	}{
+		{name: "same currency", currency: "USD", baseline: "2025-09-02", first: 100, last: 110, want: 0.1},
+		{name: "euro gains", currency: "EUR", baseline: "2025-09-02", first: 100, last: 110, want: 110*1.2/100 - 1},
+		{name: "prior-close baseline rate", currency: "EUR", baseline: "2025-08-29", first: 100, last: 100, want: 1.2/1.1 - 1},
+		{name: "no rate before the baseline", currency: "GBp", baseline: "2025-09-02", first: 100, last: 110, wantErr: errAny},
+		{name: "unavailable pair", currency: "JPY", baseline: "2025-09-02", first: 100, last: 110, wantErr: errFXUnavailable},
+		{name: "no currency", currency: "", baseline: "2025-09-02", first: 100, last: 110, wantErr: errAny},
+	}
+	c := newFXCache(fx.source, "USD", septemberStart, septemberEnd)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := MTDResult{
+				Currency:     tt.currency,
+				BaselineDate: date(t, tt.baseline).Add(9*time.Hour + 30*time.Minute),
+				LastBarTime:  date(t, "2025-09-30").Add(9*time.Hour + 30*time.Minute),
+				FirstClose:   decimal.NewFromFloat(tt.first),
+				LastClose:    decimal.NewFromFloat(tt.last),
+			}
+			res.Return = computeReturn(res.FirstClose, res.LastClose, returnSimple)
+			err := c.convert(context.Background(), &res, returnSimple)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("convert: %v", err)
+			case tt.wantErr == errAny && err == nil, tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("convert = %v, want %v", err, tt.wantErr)
+			case err != nil:
+				if res.CurrencyReturn != nil {
+					t.Errorf("currency return %v set despite %v", *res.CurrencyReturn, err)
+				}
+				return
+			}
+			if res.CurrencyReturn == nil || math.Abs(*res.CurrencyReturn-tt.want) > 1e-12 {
+				t.Errorf("currency return = %v, want %v", res.CurrencyReturn, tt.want)
+			}
+		})
+	}
+}
+
+// errAny stands for any error in TestFXCacheConvert
+var errAny = errors.New("any error")
+
+func TestFXCacheFetchesOncePerPair(t *testing.T) {
+	fx := &fakeFX{series: map[string]fxSeries{
+		"EURUSD": fxRates(t, "2025-08-29", 1.10),
+		"CADUSD": fxRates(t, "2025-08-29", 0.70),
+	}}
+	c := newFXCache(fx.source, "USD", septemberStart, septemberEnd)
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := MTDResult{
+				Currency:     []string{"EUR", "CAD", "JPY", "USD"}[i%4],
+				BaselineDate: date(t, "2025-09-02"),
+				LastBarTime:  date(t, "2025-09-30"),
+				FirstClose:   decimal.NewFromInt(100),
+				LastClose:    decimal.NewFromInt(101),
+			}
+			c.convert(context.Background(), &res, returnSimple)
+		}()
+	}
+	wg.Wait()
+	// EUR, CAD and the missing JPY are each fetched once; USD needs no rates
+	if n := fx.fetches.Load(); n != 3 {
+		t.Errorf("%d FX fetches, want 3", n)
+	}
+}
+
+func TestRunTargetCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantEUR *float64 // CurrencyReturn of the euro-listed ticker
+	}{
+		{name: "no target"},
+		{name: "usd", target: "USD", wantEUR: ptr(120.0*1.2/100 - 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+			f.charts["EUX"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+			f.currency["EUX"] = "EUR"
+			f.charts["EURUSD=X"] = dailyBars("2025-08-20", "2025-09-30", func(i int) float64 {
+				if i < 9 { // Through Sep 2, the baseline
+					return 1.0
+				}
+				return 1.2
+			})
+
+			results, summary, err := runFake(t, []string{"AAA", "EUX"}, RunOptions{TargetCurrency: tt.target})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.TargetCurrency != tt.target {
+				t.Errorf("target currency = %q, want %q", summary.TargetCurrency, tt.target)
+			}
+			aaa, eux := resultFor(t, results, "AAA"), resultFor(t, results, "EUX")
+			if tt.wantEUR == nil {
+				if aaa.CurrencyReturn != nil || eux.CurrencyReturn != nil {
+					t.Errorf("currency returns set without a target: %v %v", aaa.CurrencyReturn, eux.CurrencyReturn)
+				}
+				return
+			}
+			if aaa.CurrencyReturn == nil || *aaa.CurrencyReturn != aaa.Return {
+				t.Errorf("USD ticker's currency return = %v, want its return %v", aaa.CurrencyReturn, aaa.Return)
+			}
+			if eux.CurrencyReturn == nil {
+				t.Fatal("EUR ticker has no currency return")
+			}
+			if math.Abs(*eux.CurrencyReturn-*tt.wantEUR) > 1e-12 {
+				t.Errorf("EUR ticker's currency return = %v, want %v", *eux.CurrencyReturn, *tt.wantEUR)
+			}
+			if math.Abs(eux.Return-0.2) > 1e-12 {
+				t.Errorf("native return = %v, want 0.2", eux.Return)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
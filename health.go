@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+)
+
+// healthResponse is the body of /healthz and /readyz
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// handleHealthz reports liveness: the process is up and serving, whether or
+// not any results have been loaded
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// handleReadyz reports readiness: 503 until a refresh has stored its
+// results (since startup or the last reset), so a load balancer doesn't route
+// traffic to an empty server, then 200
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		w.Header().Set("Retry-After", "30")
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "loading"})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ready"})
+}
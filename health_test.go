@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthAndReadiness(t *testing.T) {
+	writeTemplates(t, nil)
+	withConfig(t, func(c *Config) { c.ResetToken = "" })
+	s := NewServer()
+	steps := []struct {
+		name       string
+		do         func()
+		wantReady  int
+		wantStatus string
+	}{
+		{name: "startup", do: func() {}, wantReady: http.StatusServiceUnavailable, wantStatus: "loading"},
+		{name: "first refresh", do: func() {
+			s.UpdateResults([]Result{{Ticker: "AAA", Sector: "Tech"}}, RunSummary{RunID: "r1"})
+		}, wantReady: http.StatusOK, wantStatus: "ready"},
+		{name: "empty refresh stays ready", do: func() {
+			s.UpdateResults(nil, RunSummary{RunID: "r2"})
+		}, wantReady: http.StatusOK, wantStatus: "ready"},
+		{name: "reset", do: func() {
+			s.handleReset(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/reset", nil))
+		}, wantReady: http.StatusServiceUnavailable, wantStatus: "loading"},
+		{name: "refresh after reset", do: func() {
+			s.UpdateResults([]Result{{Ticker: "AAA", Sector: "Tech"}}, RunSummary{RunID: "r3"})
+		}, wantReady: http.StatusOK, wantStatus: "ready"},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.do()
+
+			rec := httptest.NewRecorder()
+			s.handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("healthz = %d, want 200 regardless of readiness", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			s.handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var body healthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != step.wantReady || body.Status != step.wantStatus {
+				t.Errorf("readyz = %d %q, want %d %q", rec.Code, body.Status, step.wantReady, step.wantStatus)
+			}
+			if retry := rec.Header().Get("Retry-After"); (retry != "") != (step.wantReady != http.StatusOK) {
+				t.Errorf("Retry-After = %q with status %d", retry, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// storedRun is a completed refresh kept in memory for /api/diff
+type storedRun struct {
+	Summary   RunSummary
+	Results   []Result
+	UpdatedAt time.Time
+}
+
+// recordRun appends a run to the history, dropping the oldest beyond cfg.RunHistory.
+// The caller must hold s.mu.
+func (s *Server) recordRun(run storedRun) {
+	if cfg.RunHistory <= 0 {
+		return
+	}
+	s.history = append(s.history, run)
+	if over := len(s.history) - cfg.RunHistory; over > 0 {
+		s.history = append(s.history[:0:0], s.history[over:]...)
+	}
+}
+
+// findRun returns the stored run with the given ID. The caller must hold s.mu.
+func (s *Server) findRun(id string) (storedRun, bool) {
+	for _, run := range s.history {
+		if run.Summary.RunID == id {
+			return run, true
+		}
+	}
+	return storedRun{}, false
+}
+
+// RunDiff is the JSON body returned by /api/diff
+type RunDiff struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Tickers SnapshotDiff  `json:"tickers"`
+	Sectors []SectorDelta `json:"sectors"`
+
+	Truncated bool `json:"truncated,omitempty"` // A ticker list was cut to OMAHA_MAX_RESPONSE_RESULTS
+}
+
+// capDiff cuts each of diff's ticker lists to the response cap, marking the
+// response with their combined length when any of them is cut
+func capDiff(w http.ResponseWriter, diff SnapshotDiff) (SnapshotDiff, bool) {
+	total := len(diff.Changes) + len(diff.Added) + len(diff.Removed)
+	var changes, added, removed bool
+	diff.Changes, changes = capItems(w, diff.Changes)
+	diff.Added, added = capItems(w, diff.Added)
+	diff.Removed, removed = capItems(w, diff.Removed)
+	truncated := changes || added || removed
+	if truncated {
+		markTruncated(w, total)
+	}
+	return diff, truncated
+}
+
+// handleDiff compares two stored runs given by ?from= and ?to= run IDs
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fromID, toID := query.Get("from"), query.Get("to")
+	for _, p := range []struct{ name, value string }{{"from", fromID}, {"to", toID}} {
+		if p.value == "" {
+			writeParamError(w, p.name, p.value, "a run ID")
+			return
+		}
+	}
+
+	s.mu.RLock()
+	from, okFrom := s.findRun(fromID)
+	to, okTo := s.findRun(toID)
+	s.mu.RUnlock()
+
+	switch {
+	case !okFrom:
+		writeJSONError(w, http.StatusNotFound, "unknown run "+fromID)
+		return
+	case !okTo:
+		writeJSONError(w, http.StatusNotFound, "unknown run "+toID)
+		return
+	}
+
+	tickers, truncated := capDiff(w, diffResults(from.Results, to.Results))
+	writeJSON(w, http.StatusOK, RunDiff{
+		From:      fromID,
+		To:        toID,
+		Tickers:   tickers,
+		Sectors:   diffSectors(calculateSectorReturns(from.Results), calculateSectorReturns(to.Results)),
+		Truncated: truncated,
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCapDiff(t *testing.T) {
+	diff := SnapshotDiff{
+		Changes: make([]ReturnDelta, 4),
+		Added:   []string{"A", "B"},
+		Removed: []string{"C"},
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		wantLens      [3]int
+		wantTruncated bool
+	}{
+		{name: "under the cap", limit: 4, wantLens: [3]int{4, 2, 1}},
+		{name: "changes cut", limit: 3, wantLens: [3]int{3, 2, 1}, wantTruncated: true},
+		{name: "every list cut", limit: 1, wantLens: [3]int{1, 1, 1}, wantTruncated: true},
+		{name: "cap disabled", limit: 0, wantLens: [3]int{4, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.MaxResponseResults = tt.limit })
+			rec := httptest.NewRecorder()
+			got, truncated := capDiff(rec, diff)
+			if lens := [3]int{len(got.Changes), len(got.Added), len(got.Removed)}; lens != tt.wantLens || truncated != tt.wantTruncated {
+				t.Errorf("lengths %v, truncated %t; want %v, %t", lens, truncated, tt.wantLens, tt.wantTruncated)
+			}
+			// The total covers all three lists, not just the last one cut
+			if total := rec.Header().Get(headerTotal); tt.wantTruncated && total != "7" {
+				t.Errorf("total header = %q, want 7", total)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Optional metric names, as listed in OMAHA_METRICS and ?metrics=
+const (
+	metricVolatility       = "volatility"
+	metricMaxDrawdown      = "max_drawdown"
+	metricRelativeToSector = "relative_to_sector"
+)
+
+// metricsNone selects none of the optional metrics
+const metricsNone = "none"
+
+// MetricSet selects the optional per-ticker metrics a run computes. The
+// zero value computes none of them.
+type MetricSet struct {
+	Volatility       bool
+	MaxDrawdown      bool
+	RelativeToSector bool
+}
+
+// allMetrics is the default: every optional metric
+var allMetrics = MetricSet{Volatility: true, MaxDrawdown: true, RelativeToSector: true}
+
+// series reports whether any requested metric needs the per-bar return
+// series, which getMTDReturn otherwise skips collecting
+func (m MetricSet) series() bool {
+	return m.Volatility || m.MaxDrawdown
+}
+
+// names lists the selected metrics, for the run summary
+func (m MetricSet) names() []string {
+	names := []string{}
+	if m.Volatility {
+		names = append(names, metricVolatility)
+	}
+	if m.MaxDrawdown {
+		names = append(names, metricMaxDrawdown)
+	}
+	if m.RelativeToSector {
+		names = append(names, metricRelativeToSector)
+	}
+	return names
+}
+
+// clear drops the metrics m doesn't select from r, e.g. from a checkpointed
+// result of a run that computed more
+func (m MetricSet) clear(r *Result) {
+	if !m.Volatility {
+		r.Volatility = nil
+	}
+	if !m.MaxDrawdown {
+		r.MaxDrawdown = nil
+	}
+	if !m.RelativeToSector {
+		r.RelativeToSector = nil
+	}
+}
+
+// parseMetrics parses a comma-separated list of metric names. Empty selects
+// every metric and "none" selects none.
+func parseMetrics(s string) (MetricSet, error) {
+	if strings.TrimSpace(s) == "" {
+		return allMetrics, nil
+	}
+	var m MetricSet
+	for _, name := range splitList(strings.ToLower(s)) {
+		switch name {
+		case metricVolatility:
+			m.Volatility = true
+		case metricMaxDrawdown:
+			m.MaxDrawdown = true
+		case metricRelativeToSector:
+			m.RelativeToSector = true
+		case metricsNone:
+		default:
+			return MetricSet{}, fmt.Errorf("unknown metric %q", name)
+		}
+	}
+	return m, nil
+}
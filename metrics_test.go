@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMetrics(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    MetricSet
+		wantErr bool
+	}{
+		{value: "", want: allMetrics},
+		{value: "none", want: MetricSet{}},
+		{value: "volatility", want: MetricSet{Volatility: true}},
+		{value: "Max_Drawdown, relative_to_sector", want: MetricSet{MaxDrawdown: true, RelativeToSector: true}},
+		{value: "volatility,max_drawdown,relative_to_sector", want: allMetrics},
+		{value: "beta", wantErr: true},
+		{value: "volatility,beta", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseMetrics(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseMetrics(%q) = %+v, %v; want %+v", tt.value, got, err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			// names round-trips through parseMetrics
+			if again, err := parseMetrics(strings.Join(append(got.names(), metricsNone), ",")); err != nil || again != got {
+				t.Errorf("names %v parsed back as %+v, %v", got.names(), again, err)
+			}
+		})
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	tests := []struct {
+		metrics     string
+		want        MetricSet
+		wantColumns []string // Optional CSV columns expected; the others must be absent
+	}{
+		{metrics: "", want: allMetrics, wantColumns: []string{"Volatility_%", "Max_Drawdown_%", "Relative_To_Sector_%"}},
+		{metrics: "none"},
+		{metrics: "volatility", want: MetricSet{Volatility: true}, wantColumns: []string{"Volatility_%"}},
+		{metrics: "relative_to_sector", want: MetricSet{RelativeToSector: true}, wantColumns: []string{"Relative_To_Sector_%"}},
+		{metrics: "bogus", want: allMetrics, wantColumns: []string{"Volatility_%", "Max_Drawdown_%", "Relative_To_Sector_%"}},
+	}
+	allColumns := []string{"Volatility_%", "Max_Drawdown_%", "Relative_To_Sector_%"}
+	for _, tt := range tests {
+		t.Run(tt.metrics, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = laborDayBars
+			f.charts["BBB"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 200 - float64(i%3) })
+			results, summary, err := runFake(t, []string{"AAA", "BBB"}, RunOptions{Metrics: tt.metrics})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(summary.Metrics, tt.want.names()) {
+				t.Errorf("summary metrics = %v, want %v", summary.Metrics, tt.want.names())
+			}
+			for _, r := range results {
+				if (r.Volatility != nil) != tt.want.Volatility || (r.MaxDrawdown != nil) != tt.want.MaxDrawdown ||
+					(r.RelativeToSector != nil) != tt.want.RelativeToSector {
+					t.Errorf("%s: volatility %v, drawdown %v, relative %v; want %+v",
+						r.Ticker, r.Volatility, r.MaxDrawdown, r.RelativeToSector, tt.want)
+				}
+			}
+			data, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, _, _ := strings.Cut(string(data), "\n")
+			for _, col := range allColumns {
+				if strings.Contains(header, col) != slices.Contains(tt.wantColumns, col) {
+					t.Errorf("column %s in header %q, want %t", col, header, slices.Contains(tt.wantColumns, col))
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,32 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"math"
+	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/gocolly/colly"
+	finance "github.com/piquette/finance-go"
 	"github.com/piquette/finance-go/chart"
 	"github.com/piquette/finance-go/datetime"
 	"github.com/shopspring/decimal"
@@ -21,27 +36,58 @@ import (
 // Configuration
 // ------------------------------------
 const (
-	maxErrors   = 20   // Maximum number of errors before giving up
-	debug       = false // Set to true for debug output
-	maxWorkers  = 10    // Maximum number of concurrent workers
-)
+	maxErrors  = 20    // Maximum number of errors before giving up
+	debug      = false // Set to true for debug output
+	maxWorkers = 10    // Maximum number of concurrent workers
 
-// Global error counter
-var errorCount int
+	outputFile     = "sp500_mtd_returns.csv"  // CSV written after each run
+	outputXLSXFile = "sp500_mtd_returns.xlsx" // Workbook written alongside the CSV when enabled
+	outputPivot    = "sp500_mtd_pivot.csv"    // Sector-by-metric CSV written alongside the CSV when enabled
+)
 
 // ------------------------------------
 // Step 1: Get S&P 500 tickers
 // ------------------------------------
-func getSP500Tickers() ([]string, []string, error) {
-	url := "https://en.wikipedia.org/wiki/List_of_S%26P_500_companies"
-	c := colly.NewCollector()
-	var tickers []string
-	var sectors []string
-	errorCount = 0 // Reset error counter at start
-
-	c.OnHTML("table.wikitable tbody tr", func(e *colly.HTMLElement) {
+func getSP500Tickers(ctx context.Context) (Universe, error) {
+	page := wikipediaPages[indexSP500]
+	if cfg.SP500Page != "" {
+		var err error
+		if page, err = pageURL(cfg.SP500Page); err != nil {
+			return Universe{}, fmt.Errorf("invalid OMAHA_SP500_PAGE: %v", err)
+		}
+	}
+	return scrapeWikipediaIndex(ctx, "S&P 500", page)
+}
+
+// pageURL turns a constituents page location into a URL: http(s) and file
+// URLs are kept, and anything else is taken as a local file path
+func pageURL(page string) (string, error) {
+	if strings.HasPrefix(page, "http://") || strings.HasPrefix(page, "https://") || strings.HasPrefix(page, "file://") {
+		return page, nil
+	}
+	path, err := filepath.Abs(page)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(), nil
+}
+
+// scrapeWikipediaIndex scrapes the constituents table of a Wikipedia index page.
+// Each scrape counts its own errors, so several pages can be scraped concurrently.
+func scrapeWikipediaIndex(ctx context.Context, label, url string) (Universe, error) {
+	c := newCollector(ctx)
+	var u Universe
+	seen := make(map[string]bool)
+	errorCount := errorLimit{max: maxErrors}
+	tables := 0
+
+	parseRow := func(_ int, e *colly.HTMLElement) {
 		// Get the first column (ticker symbol) from each row
 		ticker := e.ChildText("td:nth-child(1) a")
+		name := e.ChildText("td:nth-child(2)")
 		sector := e.ChildText("td:nth-child(3)")
 		// If no link, try getting the text directly
 		if ticker == "" {
@@ -49,107 +95,460 @@ func getSP500Tickers() ([]string, []string, error) {
 		}
 		// Clean up and validate the ticker
 		ticker = strings.TrimSpace(ticker)
-		if ticker != "" && ticker != "Symbol" && len(ticker) < 10 { // Basic validation
-			tickers = append(tickers, ticker)
-			sectors = append(sectors, sector)
+		if ticker != "" && ticker != "Symbol" && len(ticker) < 10 && !seen[ticker] { // Basic validation
+			// Keep the slices aligned even when a row has no sector cell
+			sector = strings.TrimSpace(sector)
+			if sector == "" {
+				sector = unknownSector
+			}
+			seen[ticker] = true
+			u.add(ticker, sector, strings.TrimSpace(name))
+		}
+	}
+
+	// Only tables headed by a symbol column are parsed, so a mirror or snapshot
+	// whose layout has drifted fails loudly instead of yielding junk tickers
+	c.OnHTML("table.wikitable", func(t *colly.HTMLElement) {
+		header := strings.ToLower(t.ChildText("tr:first-child th"))
+		if !strings.Contains(header, "symbol") && !strings.Contains(header, "ticker") {
+			return
 		}
+		tables++
+		t.ForEach("tbody tr", parseRow)
 	})
 
 	// Set error handler
 	c.OnError(func(r *colly.Response, err error) {
-		errorCount++
-		log.Printf("Error %d/%d - URL: %s failed with response: %v\nError: %v", 
-			errorCount, maxErrors, r.Request.URL, r.StatusCode, err)
-		
-		if errorCount >= maxErrors {
+		n, reached := errorCount.record()
+		log.Printf("Error %d/%d - URL: %s failed with response: %v\nError: %v",
+			n, maxErrors, r.Request.URL, r.StatusCode, err)
+
+		if reached {
 			log.Fatalf("Reached maximum number of errors (%d). Exiting...", maxErrors)
 		}
 	})
 
-	fmt.Println("Fetching S&P 500 tickers from Wikipedia...")
-	if err := c.Visit(url); err != nil {
-		return nil, nil, fmt.Errorf("error visiting %s: %v", url, err)
+	fmt.Printf("Fetching %s tickers from Wikipedia...\n", label)
+	err := c.Visit(url)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Universe{}, ctxErr
+	}
+	if err != nil {
+		return Universe{}, fmt.Errorf("error visiting %s: %v", url, err)
 	}
 
-	if len(tickers) == 0 {
-		return nil, nil, fmt.Errorf("no tickers found on the page")
+	if tables == 0 {
+		return Universe{}, fmt.Errorf("no constituents table (a wikitable with a Symbol column) on %s", url)
 	}
+	if u.Len() == 0 {
+		return Universe{}, fmt.Errorf("no tickers found on the page")
+	}
+	if err := u.aligned(); err != nil {
+		return Universe{}, err
+	}
+
+	fmt.Printf("Found %d tickers\n", u.Len())
+	return u, nil
+}
 
-	fmt.Printf("Found %d tickers\n", len(tickers))
-	return tickers, sectors, nil
+// errorLimit counts scrape errors. It is atomic so an async collector could
+// share it safely.
+type errorLimit struct {
+	count atomic.Int64
+	max   int64
+}
+
+// record counts an error, returning the new count and whether this error is
+// the one that reached max; only one caller ever sees true
+func (l *errorLimit) record() (int64, bool) {
+	n := l.count.Add(1)
+	return n, n == l.max
 }
 
 // ------------------------------------
 // Step 2: Get month start and end
 // ------------------------------------
 func getMonthRange(year int, month time.Month, day int) (time.Time, time.Time) {
-	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	start := time.Date(year, month, day, 0, 0, 0, 0, cfg.Location)
 	end := start.AddDate(0, 1, -1)
 	return start, end
 }
 
+// fixedClock parses a YYYY-MM-DD date into a clock that always returns the end of that day
+func fixedClock(date string) (func() time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", date, cfg.Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD): %v", date, err)
+	}
+	t = t.Add(24*time.Hour - time.Nanosecond)
+	return func() time.Time { return t }, nil
+}
+
+// ErrInvalidRange marks date windows that can't produce data
+var ErrInvalidRange = errors.New("invalid date range")
+
+// validateRange rejects windows that end before they start or lie entirely after now
+func validateRange(start, end, now time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange,
+			end.Format("2006-01-02"), start.Format("2006-01-02"))
+	}
+	if start.After(now) {
+		return fmt.Errorf("%w: window starting %s is in the future", ErrInvalidRange,
+			start.Format("2006-01-02"))
+	}
+	return nil
+}
+
 // ------------------------------------
 // Step 3: Compute MTD return from Yahoo
 // ------------------------------------
 type MTDResult struct {
-	Return     float64
-	BarCount   int
-	FirstClose decimal.Decimal
-	LastClose  decimal.Decimal
+	Return      float64
+	BarCount    int
+	FirstClose  decimal.Decimal
+	LastClose   decimal.Decimal
+	PriceChange decimal.Decimal // LastClose - FirstClose
+
+	BaselineDate time.Time // Date of the bar FirstClose was taken from
+
+	// Computed bar by bar (see seriesStats) rather than from a retained series
+	Volatility  *float64 // Annualized volatility of daily returns since VolatilityStart, or in the window; nil below two returns
+	MaxDrawdown *float64 // Largest fall from a running peak since the baseline, <= 0
+
+	// Sessions in the window that closed above or below the previous close
+	UpDays   int
+	DownDays int
+
+	PeriodReturns map[string]float64 // Return per extra period (e.g. "qtd"), keyed by period name
+	Series        []float64          // Cumulative return at each bar in the window, when requested
+	LastBarTime   time.Time          // Timestamp of the last bar returned
+	AvgVolume     float64            // Mean daily volume over the window's bars that report volume
+
+	Currency       string   // Currency the closes are quoted in, as Yahoo reports it (e.g. "USD", "GBp")
+	CurrencyReturn *float64 // Return in the run's target currency; see fxCache.convert
+}
+
+// FetchOptions controls what getMTDReturn computes beyond the window return
+type FetchOptions struct {
+	// PeriodStarts maps extra period names to their start dates. The chart is
+	// fetched once from the earliest start and sliced for each period.
+	PeriodStarts map[string]time.Time
+
+	// Series retains the normalized cumulative return series for sparklines.
+	// It is off by default because it grows memory and payload size.
+	Series bool
+
+	// ForwardFill repeats the previous close on trading days with no bar
+	// (e.g. halts), in Series and in the daily returns behind Volatility, so
+	// a gap counts as flat sessions rather than one multi-day move. The window
+	// return always uses the actual first and last closes.
+	ForwardFill bool
+
+	ReturnType string // returnSimple (default) or returnLog
+
+	// ReturnBasis selects the per-bar price returns are measured on (see basisClose)
+	ReturnBasis string
+
+	// PaddingDays extends the fetch start back so the first trading day of the
+	// window is never clipped. Bars before the requested start are still
+	// ignored, so the baseline is the first bar on or after it.
+	PaddingDays int
+
+	// Baseline selects the close the return is measured from (see baselineFirstInWindow)
+	Baseline string
+
+	// VolatilityStart, when before the window start, widens the fetch so
+	// Volatility covers the daily returns from this date on. The return and
+	// the other window metrics still use only the window's bars.
+	VolatilityStart time.Time
+
+	// Metrics selects the optional metrics computed; without Volatility or
+	// MaxDrawdown the per-bar return series isn't collected at all
+	Metrics MetricSet
+}
+
+// Baseline strategies for the window's starting close
+const (
+	// baselineFirstInWindow uses the close of the first bar on or after the start,
+	// so the first day's move is excluded from the return
+	baselineFirstInWindow = "first-in-window"
+	// baselinePriorClose uses the last close before the start (e.g. the prior
+	// month-end for MTD), so the first day's move is included
+	baselinePriorClose = "prior-close"
+	// baselineNearest uses whichever of the two is closer in time to the start,
+	// preferring the first bar in the window on a tie
+	baselineNearest = "nearest"
+)
+
+// priorCloseLookback is how far before the start the fetch reaches to find a prior close
+const priorCloseLookback = 7
+
+// Return bases: the price taken from each bar
+const (
+	// basisClose uses each bar's close
+	basisClose = "close"
+	// basisVWAP approximates each day's volume-weighted average price with the
+	// typical price (high+low+close)/3, since daily bars carry no intraday
+	// volume profile. The return then runs from the first day's VWAP to the
+	// last day's, a closer match to what a trader working an order could get.
+	basisVWAP = "vwap"
+)
+
+// barPrice returns the price of bar under basis
+func barPrice(bar *finance.ChartBar, basis string) decimal.Decimal {
+	if basis == basisVWAP && !bar.High.IsZero() && !bar.Low.IsZero() {
+		return bar.High.Add(bar.Low).Add(bar.Close).Div(decimal.NewFromInt(3))
+	}
+	return bar.Close
+}
+
+// maxBadBarFraction is the share of malformed bars above which a ticker's
+// data is rejected instead of returned without them
+const maxBadBarFraction = 0.2
+
+// Return types
+const (
+	returnSimple = "simple" // last/first - 1
+	returnLog    = "log"    // ln(last/first)
+)
+
+// computeReturn returns the return from first to last close in the given return type
+func computeReturn(first, last decimal.Decimal, returnType string) float64 {
+	ratio := last.Div(first)
+	if returnType == returnLog {
+		r, _ := ratio.Float64()
+		return math.Log(r)
+	}
+	r, _ := ratio.Sub(decimal.NewFromInt(1)).Float64()
+	return r
+}
+
+// annualizeReturn scales a return over sessions trading days to a year of
+// tradingDaysPerYear sessions: compounded for simple returns, linearly for log returns
+func annualizeReturn(ret float64, sessions int, returnType string) float64 {
+	years := float64(sessions) / tradingDaysPerYear
+	if returnType == returnLog {
+		return ret / years
+	}
+	return math.Pow(1+ret, 1/years) - 1
 }
 
-func getMTDReturn(ticker string, start, end time.Time) (MTDResult, error) {
+func getMTDReturn(ctx context.Context, ticker string, start, end time.Time, fopts FetchOptions) (MTDResult, error) {
 	if debug {
-		fmt.Printf("🔍 Fetching data for %s from %s to %s\n", ticker, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		fetchStart := time.Now()
+		fmt.Printf("🔍 [%s] %s fetching %s from %s to %s\n", traceLabel(ctx), fetchStart.Format(time.RFC3339Nano),
+			ticker, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		defer func() {
+			fmt.Printf("🔍 [%s] %s done %s in %v\n", traceLabel(ctx), time.Now().Format(time.RFC3339Nano),
+				ticker, time.Since(fetchStart))
+		}()
+	}
+
+	// Widen the fetch to cover every requested period, plus any padding
+	fetchStart := start
+	for _, ps := range fopts.PeriodStarts {
+		if ps.Before(fetchStart) {
+			fetchStart = ps
+		}
+	}
+	if fopts.Metrics.Volatility && !fopts.VolatilityStart.IsZero() && fopts.VolatilityStart.Before(fetchStart) {
+		fetchStart = fopts.VolatilityStart
 	}
-	
+	padding := fopts.PaddingDays
+	if (fopts.Baseline == baselinePriorClose || fopts.Baseline == baselineNearest) && padding < priorCloseLookback {
+		padding = priorCloseLookback
+	}
+	fetchStart = fetchStart.AddDate(0, 0, -padding)
+
 	params := &chart.Params{
+		Params:   finance.Params{Context: &ctx},
 		Symbol:   ticker,
-		Start:    datetime.FromUnix(int(start.Unix())),
-		End:      datetime.FromUnix(int(end.Unix())),
+		Start:    datetime.FromUnix(int(fetchStart.Unix())),
+		End:      datetime.FromUnix(int(end.AddDate(0, 0, 1).Unix())), // end is inclusive
 		Interval: datetime.OneDay,
 	}
 
 	iter := chart.Get(params)
 	var firstClose, lastClose decimal.Decimal
+	var lastBarTime time.Time
+	var priorClose decimal.Decimal
+	var priorTime, baselineDate time.Time
 	firstSet := false
+	baselineBefore := false // The baseline is the prior close, so the first bar's move counts
 	barCount := 0
+	periodFirst := make(map[string]decimal.Decimal, len(fopts.PeriodStarts))
+	var closes []decimal.Decimal
+	var closeTimes []time.Time
+	var volumeTotal int64
+	volumeBars := 0
+	totalBars, badBars := 0, 0
+	var prevPrice decimal.Decimal
+	var prevTime time.Time
+	upDays, downDays := 0, 0
+	var stats seriesStats
+	collectStats := fopts.Metrics.series()
+	// inLookback reports whether a bar is in the wider volatility lookback
+	inLookback := func(t time.Time) bool {
+		return fopts.Metrics.Volatility && !fopts.VolatilityStart.IsZero() && !t.Before(fopts.VolatilityStart)
+	}
+	// addDaily adds the return from the previous bar, preceded by a flat
+	// return for each session between them when forward-filling
+	addDaily := func(prev, price decimal.Decimal, prevBarTime, barTime time.Time) {
+		if fopts.ForwardFill && !prevBarTime.IsZero() {
+			for range missedSessions(prevBarTime, barTime) {
+				stats.addReturn(0)
+			}
+		}
+		stats.addReturn(computeReturn(prev, price, fopts.ReturnType))
+	}
 
 	for iter.Next() {
 		bar := iter.Bar()
+		barTime := time.Unix(int64(bar.Timestamp), 0).In(cfg.Location)
+		price := barPrice(bar, fopts.ReturnBasis)
+		// Malformed bars carry a zero or negative close; skip them rather than let one corrupt the return
+		totalBars++
+		if !bar.Close.IsPositive() || !price.IsPositive() {
+			badBars++
+			continue
+		}
+		for name, ps := range fopts.PeriodStarts {
+			if _, ok := periodFirst[name]; !ok && !barTime.Before(ps) {
+				periodFirst[name] = price
+			}
+		}
+		lastClose = price
+		lastBarTime = barTime
+		prev, prevBarTime := prevPrice, prevTime
+		prevPrice, prevTime = price, barTime
+
+		// Bars before the window start only feed the wider periods, the
+		// volatility lookback and the prior close
+		if barTime.Before(start) {
+			if inLookback(prevBarTime) {
+				addDaily(prev, price, prevBarTime, barTime)
+			}
+			priorClose, priorTime = price, barTime
+			continue
+		}
 		barCount++
 		if !firstSet {
-			firstClose = bar.Close
+			firstClose, baselineDate = price, barTime
 			firstSet = true
+			if !priorClose.IsZero() {
+				switch fopts.Baseline {
+				case baselinePriorClose:
+					baselineBefore = true
+				case baselineNearest:
+					baselineBefore = start.Sub(priorTime) < barTime.Sub(start)
+				}
+			}
+			if baselineBefore {
+				firstClose, baselineDate = priorClose, priorTime
+			}
+			if collectStats {
+				stats.addPrice(firstClose.InexactFloat64())
+			}
+		}
+		// The first bar's move counts only when the baseline is the prior close
+		if barCount > 1 || baselineBefore {
+			switch price.Cmp(prev) {
+			case 1:
+				upDays++
+			case -1:
+				downDays++
+			}
+		}
+		if collectStats {
+			if barCount > 1 || baselineBefore || inLookback(prevBarTime) {
+				addDaily(prev, price, prevBarTime, barTime)
+			}
+			stats.addPrice(price.InexactFloat64())
+		}
+		if fopts.Series {
+			closes = append(closes, price)
+			closeTimes = append(closeTimes, barTime)
+		}
+		// Zero-volume bars are gaps in Yahoo's data, not untraded days
+		if bar.Volume > 0 {
+			volumeTotal += int64(bar.Volume)
+			volumeBars++
 		}
-		lastClose = bar.Close
 	}
 
 	if err := iter.Err(); err != nil {
 		errMsg := fmt.Sprintf("❌ Error fetching data for %s: %v", ticker, err)
-		// Try to extract more details if it's a finance-go error
-		if ferr, ok := err.(interface{ Code() string }); ok {
-			errMsg += fmt.Sprintf(" (Code: %s)", ferr.Code())
-		}
-		if ferr, ok := err.(interface{ Detail() string }); ok {
-			errMsg += fmt.Sprintf(" (Detail: %s)", ferr.Detail())
+		// Recognized finance-go errors are returned typed so callers can branch
+		kind := classifyFetchError(err)
+		if kind != nil {
+			errMsg += fmt.Sprintf(" (%v)", kind)
 		}
 		fmt.Println(errMsg)
-		return MTDResult{Return: math.NaN()}, fmt.Errorf(errMsg)
+		if kind != nil {
+			return MTDResult{Return: math.NaN()}, fmt.Errorf("%w: %w", kind, err)
+		}
+		return MTDResult{Return: math.NaN()}, fmt.Errorf("❌ Error fetching data for %s: %w", ticker, err)
+	}
+	if badBars > 0 {
+		if float64(badBars)/float64(totalBars) > maxBadBarFraction {
+			return MTDResult{Return: math.NaN()}, fmt.Errorf("%w: %d of %d bars have a non-positive close",
+				ErrBadData, badBars, totalBars)
+		}
+		fmt.Printf("⚠️  Skipped %d of %d bars with a non-positive close for %s\n", badBars, totalBars, ticker)
 	}
 	if !firstSet || firstClose.IsZero() {
 		fmt.Printf("⚠️  No data found for %s\n", ticker)
-		return MTDResult{Return: math.NaN()}, fmt.Errorf("no data")
+		return MTDResult{Return: math.NaN()}, ErrNoData
+	}
+
+	result := MTDResult{
+		Return:      computeReturn(firstClose, lastClose, fopts.ReturnType),
+		BarCount:    barCount,
+		FirstClose:  firstClose,
+		LastClose:   lastClose,
+		PriceChange: lastClose.Sub(firstClose),
+		UpDays:      upDays,
+		DownDays:    downDays,
+		LastBarTime: lastBarTime,
+
+		BaselineDate: baselineDate,
+	}
+	if meta, ok := iter.Iter.Meta().(finance.ChartMeta); ok {
+		result.Currency = meta.Currency
+	}
+	if fopts.Metrics.MaxDrawdown {
+		result.MaxDrawdown = &stats.maxDrawdown
+	}
+	if vol, ok := stats.volatility(); ok && fopts.Metrics.Volatility {
+		result.Volatility = &vol
+	}
+	if volumeBars > 0 {
+		result.AvgVolume = float64(volumeTotal) / float64(volumeBars)
+	}
+
+	if fopts.Series {
+		if fopts.ForwardFill {
+			closes = forwardFill(closeTimes, closes)
+		}
+		result.Series = make([]float64, len(closes))
+		for i, c := range closes {
+			result.Series[i] = computeReturn(firstClose, c, fopts.ReturnType)
+		}
 	}
 
-	mtd := lastClose.Div(firstClose).Sub(decimal.NewFromInt(1))
-	mtdFloat, _ := mtd.Float64()
-	return MTDResult{
-		Return:     mtdFloat,
-		BarCount:   barCount,
-		FirstClose: firstClose,
-		LastClose:  lastClose,
-	}, nil
+	if len(fopts.PeriodStarts) > 0 {
+		result.PeriodReturns = make(map[string]float64, len(fopts.PeriodStarts))
+		for name := range fopts.PeriodStarts {
+			first, ok := periodFirst[name]
+			if !ok || first.IsZero() {
+				result.PeriodReturns[name] = math.NaN()
+				continue
+			}
+			result.PeriodReturns[name] = computeReturn(first, lastClose, fopts.ReturnType)
+		}
+	}
+	return result, nil
 }
 
 // ------------------------------------
@@ -157,84 +556,493 @@ func getMTDReturn(ticker string, start, end time.Time) (MTDResult, error) {
 // ------------------------------------
 type Result struct {
 	Ticker     string
+	Name       string // Company name; blank when the source has none
 	Sector     string
 	Return     float64
 	BarCount   int
 	FirstClose string
 	LastClose  string
+	AvgVolume  float64 // Mean daily volume over the window; 0 when no bar reported volume
+
+	PriceChange string // Absolute price change, LastClose - FirstClose
+
+	// Baseline is the run's baseline strategy and BaselineDate ("2006-01-02")
+	// the session FirstClose came from, which differs from the window start
+	// when it falls on a weekend or holiday
+	Baseline     string
+	BaselineDate string
+
+	// Sessions that closed above or below the previous close; both 0 for a single-bar window
+	UpDays   int
+	DownDays int
+
+	// ExpectedBars is the number of NYSE sessions in the window so far;
+	// Complete reports whether BarCount reached it
+	ExpectedBars int
+	Complete     bool
+
+	Indexes []string `json:",omitempty"` // Source indices, set for combined universes
+
+	ExcessReturn *float64 `json:",omitempty"` // Return minus the prorated risk-free rate, when enabled
+
+	// RelativeToSector is Return minus the sector's average return; unset for the Unknown sector
+	RelativeToSector *float64 `json:",omitempty"`
+
+	// AnnualizedReturn is Return scaled to a year, when enabled. Short windows
+	// compound small moves into huge numbers, so it is capped at
+	// ±OMAHA_MAX_ANNUALIZED_RETURN and AnnualizedCapped set when it was.
+	AnnualizedReturn *float64 `json:",omitempty"`
+	AnnualizedCapped bool     `json:",omitempty"`
+
+	// Annualized volatility of daily returns and the largest peak-to-trough
+	// fall (<= 0) in the window; unset for quoted results
+	Volatility  *float64 `json:",omitempty"`
+	MaxDrawdown *float64 `json:",omitempty"`
+
+	// Period returns, set only when requested via RunOptions.Periods
+	ReturnMTD *float64 `json:"Return_MTD,omitempty"`
+	ReturnQTD *float64 `json:"Return_QTD,omitempty"`
+	ReturnYTD *float64 `json:"Return_YTD,omitempty"`
+
+	// Stale is set when the last bar lags the window's expected last trading day
+	Stale     bool `json:",omitempty"`
+	StaleDays int  `json:",omitempty"` // Calendar days between the last bar and the expected last trading day
+
+	// Weight is the ticker's weight in the OMAHA_WEIGHTS_CSV portfolio, set
+	// only for custom-weighted runs; 0 for tickers the file doesn't list
+	Weight *float64 `json:",omitempty"`
+
+	// Currency is the ticker's quote currency and CurrencyReturn its return
+	// in the run's target currency, both set only when one is requested.
+	// CurrencyReturn is unset when the exchange rates couldn't be found.
+	Currency       string   `json:",omitempty"`
+	CurrencyReturn *float64 `json:",omitempty"`
 }
 
 type SectorReturn struct {
-	Sector      string
-	AvgReturn   float64
-	TickerCount int
+	Sector              string
+	AvgReturn           float64 // Weighted by Result.Weight in custom-weighted runs
+	GeometricMeanReturn float64 // See returnSum.geometricMean
+	TrimmedReturn       float64 // See returnSum.trimmedMean
+	TickerCount         int
 }
 
-// calculateSectorReturns calculates average returns by sector
-func calculateSectorReturns(results []Result) []SectorReturn {
-	sectorMap := make(map[string]struct {
-		totalReturn float64
-		count       int
-	})
+// GroupReturn is the average return of the results sharing a group key
+type GroupReturn struct {
+	Group               string
+	AvgReturn           float64
+	GeometricMeanReturn float64
+	TrimmedReturn       float64
+	TickerCount         int
+}
+
+// returnSum accumulates returns for arithmetic and geometric averages
+type returnSum struct {
+	total    float64
+	count    int
+	logTotal float64 // Sum of ln(1+r) over returns above -100%
+	logCount int
+	returns  []float64 // Kept for the trimmed mean
+
+	// Custom weighting: the sums of weight*r and of the weights
+	weighted    bool
+	weightTotal float64
+	weightSum   float64
+}
+
+// add adds a return; callers skip NaN
+func (a *returnSum) add(r float64) {
+	a.total += r
+	a.count++
+	a.returns = append(a.returns, r)
+	if 1+r > 0 {
+		a.logTotal += math.Log1p(r)
+		a.logCount++
+	}
+}
+
+// addResult adds a result's return, weighted when the result carries a weight
+func (a *returnSum) addResult(r Result) {
+	a.add(r.Return)
+	if r.Weight != nil {
+		a.weighted = true
+		a.weightTotal += *r.Weight * r.Return
+		a.weightSum += *r.Weight
+	}
+}
+
+// mean returns the arithmetic mean return, or for weighted sums the mean
+// weighted by each result's share of the group's total weight. A group whose
+// results all have zero weight falls back to the equal-weighted mean.
+func (a returnSum) mean() float64 {
+	if a.weighted && a.weightSum > 0 {
+		return a.weightTotal / a.weightSum
+	}
+	return a.total / float64(a.count)
+}
+
+// geometricMean returns the geometric mean of (1+r) minus 1, the constant
+// per-ticker return that compounds to the same growth. Returns of -100% or
+// worse are skipped; -1 is returned when nothing else is left.
+func (a returnSum) geometricMean() float64 {
+	if a.logCount == 0 {
+		return -1
+	}
+	return math.Expm1(a.logTotal / float64(a.logCount))
+}
 
-	// Calculate total returns per sector
+// trimmedMean returns the mean after dropping the fraction trim of returns
+// from each end, so one halted or erroneous ticker can't swing the average.
+// At least one return is always kept; a trim of 0 gives the plain mean.
+func (a returnSum) trimmedMean(trim float64) float64 {
+	return trimmedMean(a.returns, trim)
+}
+
+// trimmedMean returns the mean of returns without the fraction trim at each end
+func trimmedMean(returns []float64, trim float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	sorted := slices.Clone(returns)
+	slices.Sort(sorted)
+	k := min(int(float64(len(sorted))*max(trim, 0)), (len(sorted)-1)/2)
+	total := 0.0
+	for _, r := range sorted[k : len(sorted)-k] {
+		total += r
+	}
+	return total / float64(len(sorted)-2*k)
+}
+
+// aggregateBy averages returns over the groups keyFunc assigns, sorted by
+// average return (descending). Results with an empty key or a NaN return are skipped.
+func aggregateBy(results []Result, keyFunc func(Result) string) []GroupReturn {
+	groupMap := make(map[string]returnSum)
+
+	// Calculate total returns per group
 	for _, r := range results {
-		if r.Sector == "" {
+		key := keyFunc(r)
+		if key == "" || math.IsNaN(r.Return) {
 			continue
 		}
-		sector := sectorMap[r.Sector]
-		sector.totalReturn += r.Return
-		sector.count++
-		sectorMap[r.Sector] = sector
+		group := groupMap[key]
+		group.addResult(r)
+		groupMap[key] = group
 	}
 
 	// Calculate average returns
-	var sectorReturns []SectorReturn
-	for sector, data := range sectorMap {
-		if data.count > 0 {
-			sectorReturns = append(sectorReturns, SectorReturn{
-				Sector:      sector,
-				AvgReturn:   data.totalReturn / float64(data.count),
-				TickerCount: data.count,
-			})
-		}
+	var groupReturns []GroupReturn
+	for key, data := range groupMap {
+		groupReturns = append(groupReturns, GroupReturn{
+			Group:               key,
+			AvgReturn:           data.mean(),
+			GeometricMeanReturn: data.geometricMean(),
+			TrimmedReturn:       data.trimmedMean(cfg.TrimFraction),
+			TickerCount:         data.count,
+		})
 	}
 
 	// Sort by average return (descending)
-	sort.Slice(sectorReturns, func(i, j int) bool {
-		return sectorReturns[i].AvgReturn > sectorReturns[j].AvgReturn
+	sort.Slice(groupReturns, func(i, j int) bool {
+		return groupReturns[i].AvgReturn > groupReturns[j].AvgReturn
 	})
 
+	return groupReturns
+}
+
+// calculateSectorReturns calculates average returns by sector
+func calculateSectorReturns(results []Result) []SectorReturn {
+	groups := aggregateBy(results, func(r Result) string { return r.Sector })
+	sectorReturns := make([]SectorReturn, len(groups))
+	for i, g := range groups {
+		sectorReturns[i] = SectorReturn{
+			Sector:              g.Group,
+			AvgReturn:           g.AvgReturn,
+			GeometricMeanReturn: g.GeometricMeanReturn,
+			TrimmedReturn:       g.TrimmedReturn,
+			TickerCount:         g.TickerCount,
+		}
+	}
 	return sectorReturns
 }
 
-// writeResultsToCSV writes both individual ticker data and sector summary to a CSV file
-func writeResultsToCSV(results []Result, sectorReturns []SectorReturn, filename string) error {
-	file, err := os.Create(filename)
+// indexCapTiers maps each index universe to its market-cap tier
+var indexCapTiers = map[string]string{
+	indexSP500:       "large",
+	indexSP400:       "mid",
+	indexSP600:       "small",
+	indexRussell1000: "large",
+}
+
+// capTierKey returns a keyFunc grouping results by market-cap tier, taken from
+// the result's first source index or, for single-index runs, from index
+func capTierKey(index string) func(Result) string {
+	return func(r Result) string {
+		if len(r.Indexes) > 0 {
+			return indexCapTiers[r.Indexes[0]]
+		}
+		return indexCapTiers[index]
+	}
+}
+
+// SectorGroup is a sector's aggregate stats with its results sorted by return
+type SectorGroup struct {
+	Sector  SectorReturn `json:"sector"`
+	Results []Result     `json:"results"`
+}
+
+// groupBySector nests results under their sectors, ordered by sector average return (descending)
+func groupBySector(results []Result) []SectorGroup {
+	members := make(map[string][]Result)
+	for _, r := range results {
+		members[r.Sector] = append(members[r.Sector], r)
+	}
+
+	var groups []SectorGroup
+	for _, sr := range calculateSectorReturns(results) {
+		group := members[sr.Sector]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Return > group[j].Return
+		})
+		groups = append(groups, SectorGroup{Sector: sr, Results: group})
+	}
+	return groups
+}
+
+// CSV ticker row orderings
+const (
+	csvSortReturn = "return" // Caller's order (return-descending for a run)
+	csvSortTicker = "ticker" // Alphabetical by ticker, for diffing across months
+)
+
+// CSVOptions controls how writeResultsToCSV formats its output
+type CSVOptions struct {
+	Units  string // Human-facing return units: percent (default) or bps
+	SortBy string // Ticker row order: csvSortReturn (default) or csvSortTicker
+
+	// Benchmark, when set, is written in its own section after the sectors
+	Benchmark *Benchmark
+}
+
+// Benchmark is the return of a reference ticker (e.g. SPY) over a run's
+// window. It is fetched alongside the constituents but kept out of the
+// results and sector averages.
+type Benchmark struct {
+	Ticker string  `json:"ticker"`
+	Return float64 `json:"return"`
+}
+
+// sectorFileName turns a sector name into a filesystem-safe CSV file name,
+// e.g. "Information Technology" -> "Information_Technology.csv"
+func sectorFileName(sector string) string {
+	var b strings.Builder
+	underscore := false
+	for _, c := range sector {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' {
+			b.WriteRune(c)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" {
+		name = "sector"
+	}
+	return name + ".csv"
+}
+
+// writeSectorCSVs writes one CSV per sector into dir, each holding that
+// sector's ticker rows and its summary row, in the combined CSV's format
+func writeSectorCSVs(results []Result, sectorReturns []SectorReturn, dir string, opts CSVOptions) error {
+	members := make(map[string][]Result)
+	for _, r := range results {
+		members[r.Sector] = append(members[r.Sector], r)
+	}
+	written := make(map[string]string) // File name -> sector, to catch names that sanitize alike
+	for _, sr := range sectorReturns {
+		name := sectorFileName(sr.Sector)
+		if other, ok := written[name]; ok {
+			return fmt.Errorf("sectors %q and %q both map to %s", other, sr.Sector, name)
+		}
+		written[name] = sr.Sector
+		if err := writeResultsToCSV(members[sr.Sector], []SectorReturn{sr}, filepath.Join(dir, name), opts); err != nil {
+			return fmt.Errorf("%s: %v", sr.Sector, err)
+		}
+	}
+	return nil
+}
+
+// outputMu serializes runs' writes of their output files. Each file is
+// already replaced atomically; the lock keeps one run's CSV, sector CSVs,
+// pivot and workbook from being interleaved with another's when two runs
+// finish together.
+var outputMu sync.Mutex
+
+// writeResultsToCSV writes both individual ticker data and sector summary to a CSV file.
+// Human-facing return columns are written in opts.Units (percent or bps).
+func writeResultsToCSV(results []Result, sectorReturns []SectorReturn, filename string, opts CSVOptions) error {
+	units := opts.Units
+	results = sortResultRows(results, opts.SortBy)
+	return writeCSVFile(filename, func(w io.Writer) error {
+		return writeResultsCSV(w, results, sectorReturns, units, opts.Benchmark)
+	})
+}
+
+// writeCSVFile writes a CSV through write to a temp file in the same
+// directory and renames it into place on success, so readers never see a
+// truncated CSV
+func writeCSVFile(filename string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create CSV: %v", err)
 	}
-	defer file.Close()
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace CSV: %v", err)
+	}
+	return nil
+}
+
+// sortResultRows returns results in the given ticker row order, copying
+// rather than reordering the caller's slice
+func sortResultRows(results []Result, sortBy string) []Result {
+	if sortBy != csvSortTicker {
+		return results
+	}
+	results = append([]Result(nil), results...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Ticker < results[j].Ticker
+	})
+	return results
+}
+
+// resultColumns records which optional ticker columns a table carries.
+// Optional columns are only written when the run computed them.
+type resultColumns struct {
+	excess, relative, annualized bool
+	volatility, drawdown         bool
+	currency                     bool
+	periods                      []string
+}
+
+func newResultColumns(results []Result) resultColumns {
+	return resultColumns{
+		excess:     len(results) > 0 && results[0].ExcessReturn != nil,
+		relative:   slices.ContainsFunc(results, func(r Result) bool { return r.RelativeToSector != nil }),
+		annualized: len(results) > 0 && results[0].AnnualizedReturn != nil,
+		volatility: slices.ContainsFunc(results, func(r Result) bool { return r.Volatility != nil }),
+		drawdown:   slices.ContainsFunc(results, func(r Result) bool { return r.MaxDrawdown != nil }),
+		currency:   slices.ContainsFunc(results, func(r Result) bool { return r.Currency != "" }),
+		periods:    resultPeriods(results),
+	}
+}
+
+// header returns the ticker section's column names, shared by the CSV and workbook
+func (c resultColumns) header(units string) []string {
+	header := []string{"Ticker", "Sector", "Return", returnColumn("MTD", units), "Bars", "First_Close", "Last_Close", "Name", "Avg_Volume", "Expected_Bars", "Complete", "Price_Change", "Up_Days", "Down_Days", "Baseline", "Baseline_Date"}
+	if c.excess {
+		header = append(header, returnColumn("Excess", units))
+	}
+	if c.relative {
+		header = append(header, returnColumn("Relative_To_Sector", units))
+	}
+	if c.annualized {
+		header = append(header, returnColumn("Annualized", units), "Annualized_Capped")
+	}
+	for _, p := range c.periods {
+		header = append(header, returnColumn("Return_"+strings.ToUpper(p), units))
+	}
+	if c.volatility {
+		header = append(header, returnColumn("Volatility", units))
+	}
+	if c.drawdown {
+		header = append(header, returnColumn("Max_Drawdown", units))
+	}
+	if c.currency {
+		header = append(header, "Currency", returnColumn("Converted", units))
+	}
+	return header
+}
+
+// sectorHeader returns the sector section's column names
+func sectorHeader(units string) []string {
+	avgHeader := "Avg_Return"
+	if units == unitsBps {
+		avgHeader = "Avg_Return_bps"
+	}
+	return []string{"Sector", avgHeader, "Ticker_Count"}
+}
+
+// benchmarkHeader returns the benchmark section's column names
+func benchmarkHeader(units string) []string {
+	return []string{"Benchmark", "Return", returnColumn("MTD", units)}
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// writeResultsCSV writes the ticker and sector sections, and the benchmark
+// section when benchmark is set, to w, returning any write or flush error
+func writeResultsCSV(w io.Writer, results []Result, sectorReturns []SectorReturn, units string, benchmark *Benchmark) error {
+	writer := csv.NewWriter(w)
+	cols := newResultColumns(results)
 
 	// Write header for ticker data
-	if err := writer.Write([]string{"Ticker", "Sector", "Return", "MTD_%", "Bars", "First_Close", "Last_Close"}); err != nil {
+	if err := writer.Write(cols.header(units)); err != nil {
 		return err
 	}
 
 	// Write individual ticker data
 	for _, r := range results {
-		if err := writer.Write([]string{
+		row := []string{
 			r.Ticker,
 			r.Sector,
-			fmt.Sprintf("%.6f", r.Return),
-			fmt.Sprintf("%.2f%%", r.Return*100),
+			formatNumber("%.6f", r.Return),
+			formatReturn(r.Return, units),
 			fmt.Sprintf("%d", r.BarCount),
 			r.FirstClose,
 			r.LastClose,
-		}); err != nil {
+			r.Name,
+			formatNumber("%.0f", r.AvgVolume),
+			fmt.Sprintf("%d", r.ExpectedBars),
+			strconv.FormatBool(r.Complete),
+			r.PriceChange,
+			strconv.Itoa(r.UpDays),
+			strconv.Itoa(r.DownDays),
+			r.Baseline,
+			r.BaselineDate,
+		}
+		if cols.excess {
+			row = append(row, formatOptionalReturn(r.ExcessReturn, units))
+		}
+		if cols.relative {
+			row = append(row, formatOptionalReturn(r.RelativeToSector, units))
+		}
+		if cols.annualized {
+			row = append(row, formatOptionalReturn(r.AnnualizedReturn, units), strconv.FormatBool(r.AnnualizedCapped))
+		}
+		for _, p := range cols.periods {
+			row = append(row, formatOptionalReturn(periodReturn(r, p), units))
+		}
+		if cols.volatility {
+			row = append(row, formatOptionalReturn(r.Volatility, units))
+		}
+		if cols.drawdown {
+			row = append(row, formatOptionalReturn(r.MaxDrawdown, units))
+		}
+		if cols.currency {
+			row = append(row, r.Currency, formatOptionalReturn(r.CurrencyReturn, units))
+		}
+		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
@@ -245,7 +1053,7 @@ func writeResultsToCSV(results []Result, sectorReturns []SectorReturn, filename
 	}
 
 	// Write sector summary header
-	if err := writer.Write([]string{"Sector", "Avg_Return", "Ticker_Count"}); err != nil {
+	if err := writer.Write(sectorHeader(units)); err != nil {
 		return err
 	}
 
@@ -253,114 +1061,920 @@ func writeResultsToCSV(results []Result, sectorReturns []SectorReturn, filename
 	for _, sr := range sectorReturns {
 		if err := writer.Write([]string{
 			sr.Sector,
-			fmt.Sprintf("%.2f%%", sr.AvgReturn*100),
+			formatReturn(sr.AvgReturn, units),
 			fmt.Sprintf("%d", sr.TickerCount),
 		}); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
-
-// getMTDResults fetches month-to-date returns for a specific month and year
-// If year and month are 0, it will use the previous month
-func getMTDResults(year int, month time.Month, day int) ([]Result, error) {
+	// The benchmark gets its own section, so it can't be read as a constituent or a sector
+	if benchmark != nil {
+		if err := writer.Write([]string{""}); err != nil {
+			return err
+		}
+		if err := writer.Write(benchmarkHeader(units)); err != nil {
+			return err
+		}
+		if err := writer.Write([]string{
+			benchmark.Ticker,
+			formatNumber("%.6f", benchmark.Return),
+			formatReturn(benchmark.Return, units),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// RunOptions holds per-run settings supplied by the caller of getMTDResults
+type RunOptions struct {
+	Exclude []string     // Additional tickers or sectors to skip for this run
+	Source  TickerSource // Ticker universe; overrides Index when set
+	Index   string       // Named universe (see validIndex), or a comma-separated combination; defaults to sp500
+	Units   string       // CSV return units (percent or bps); defaults to the configured units
+	Periods []string     // Extra periods (mtd, qtd, ytd) computed from the same fetch
+	Fresh   bool         // Bypass the ticker cache and checkpoint, re-fetching everything
+
+	// TrailingDays, when positive, replaces the calendar window with the last
+	// N trading days ending today (per Now)
+	TrailingDays int
+
+	// Now is the clock used for the "previous month" default and range checks.
+	// Defaults to time.Now; pin it to backtest a run as of an earlier date.
+	Now func() time.Time
+
+	// ReturnBasis selects close (default) or vwap prices for the return
+	ReturnBasis string
+
+	// ReturnType selects simple (default) or log returns. Sector and index
+	// averages of log returns are mean log returns, not mean simple returns.
+	ReturnType string
+
+	// Baseline selects first-in-window (default) or prior-close as the starting close
+	Baseline string
+
+	// ForwardFill treats trading days with no bar as flat sessions in the
+	// daily returns behind Volatility (see FetchOptions.ForwardFill); nil
+	// uses OMAHA_FORWARD_FILL, so a request can turn it off as well as on
+	ForwardFill *bool
+
+	// Limit, when positive, fetches only the first N tickers (for testing)
+	Limit int
+
+	// MinVolume, when positive, drops tickers whose average daily volume is below it
+	MinVolume float64
+
+	// MinPrice, when positive, drops tickers whose last close is below it
+	MinPrice float64
+
+	// Sample, when positive, fetches a random sample of N tickers for a quick
+	// estimate. Stratify samples each sector in proportion to its size and
+	// reports a sector-weighted StratifiedReturn. Sampled runs skip checkpoints.
+	Sample   int
+	Stratify bool
+
+	// OnResult, when set, is called with each valid result as it completes.
+	// It runs on the collecting goroutine, so it must not block.
+	OnResult func(Result)
+
+	// Annualize sets each result's AnnualizedReturn
+	Annualize bool
+
+	// FetchMode selects chart (default) or quote requests; see fetchQuote
+	FetchMode string
+
+	// ClampEnd moves the reported window end back to the last trading day
+	// any ticker has data for, when that is before the requested end (e.g.
+	// an end of today or later), so "as of" matches the data
+	ClampEnd bool
+
+	// Progress, when set, is reset and then advanced as each ticker finishes
+	Progress *RunProgress
+
+	// FetchOrder selects scrape (default), alphabetical or sector order for
+	// fetching; it changes only the order results stream in
+	FetchOrder string
+
+	// Seed, when non-zero, seeds the run's sample and retry jitter so both
+	// can be reproduced; zero seeds from the clock. See RunSummary.Seed.
+	Seed uint64
+
+	// Weighting selects equal (default) or custom averages; custom weights
+	// sector and index returns by the OMAHA_WEIGHTS_CSV portfolio
+	Weighting string
+
+	// Metrics lists the optional metrics to compute (see parseMetrics);
+	// empty uses OMAHA_METRICS
+	Metrics string
+
+	// TargetCurrency, an ISO 4217 code such as "EUR", adds each result's
+	// CurrencyReturn converted at historical FX rates; empty uses
+	// OMAHA_TARGET_CURRENCY
+	TargetCurrency string
+}
+
+// excludeTickers drops tickers whose symbol or sector matches an entry in exclude
+// (case-insensitive). Symbols are normalized like scraped ones, so BRK.B
+// matches BRK-B. It returns the filtered universe and the number removed.
+func excludeTickers(u Universe, exclude []string) (Universe, int) {
+	if len(exclude) == 0 {
+		return u, 0
+	}
+
+	skip := make(map[string]bool, len(exclude))
+	for _, e := range exclude {
+		skip[strings.ToUpper(strings.TrimSpace(e))] = true
+		if ticker, err := normalizeSymbol(e, cfg.SymbolSuffixes); err == nil {
+			skip[ticker] = true
+		}
+	}
+
+	var kept Universe
+	for i, ticker := range u.Tickers {
+		if skip[strings.ToUpper(ticker)] || skip[strings.ToUpper(u.Sectors[i])] {
+			continue
+		}
+		kept.add(ticker, u.Sectors[i], u.Names[i])
+	}
+	return kept, u.Len() - kept.Len()
+}
+
+// ErrScrape marks failures to build the ticker universe (e.g. Wikipedia unreachable)
+var ErrScrape = errors.New("ticker scrape failed")
+
+// RunSummary describes the outcome of a getMTDResults run
+type RunSummary struct {
+	RunID         string  `json:"run_id"`
+	TickersCached bool    `json:"tickers_cached"`  // Ticker list was served from the cache
+	Tickers       int     `json:"tickers"`         // Tickers in the universe after exclusions
+	Fetched       int     `json:"fetched"`         // Tickers with a valid result
+	Failed        int     `json:"failed"`          // Tickers whose fetch failed
+	Suspect       int     `json:"suspect"`         // Tickers held for review with implausible returns
+	IndexReturn   float64 `json:"index_return"`    // Mean return of fetched tickers, equal- or custom-weighted
+	TrimmedReturn float64 `json:"trimmed_return"`  // IndexReturn without the OMAHA_TRIM_FRACTION extremes at each end
+	ReturnType    string  `json:"return_type"`     // "simple" or "log"; averages of log returns are mean log returns
+	ReturnBasis   string  `json:"return_basis"`    // "close" or "vwap"
+	FetchMode     string  `json:"fetch_mode"`      // "chart" or "quote", as actually used
+	FetchOrder    string  `json:"fetch_order"`     // "scrape", "alphabetical" or "sector"
+	Weighting     string  `json:"weighting"`       // "equal" or "custom", as actually used
+	Index         string  `json:"index,omitempty"` // Index universe; empty for a custom ticker source
+
+	// Metrics lists the optional metrics computed; see RunOptions.Metrics
+	Metrics []string `json:"metrics"`
+
+	// TargetCurrency is the currency of each result's CurrencyReturn, when requested
+	TargetCurrency string `json:"target_currency,omitempty"`
+
+	// Sampled runs fetch only part of the universe, so their averages are estimates
+	Sampled    bool      `json:"sampled,omitempty"`
+	Stratified bool      `json:"stratified,omitempty"`
+	Population int       `json:"population,omitempty"` // Universe size the sample was drawn from
+	Seed       uint64    `json:"seed"`                 // Seeds the sample and retry jitter; pass as RunOptions.Seed to reproduce them
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+
+	// StratifiedReturn extrapolates a stratified sample to the universe by
+	// weighting each sector's mean by its share; IndexReturn stays the sample's own
+	StratifiedReturn *float64 `json:"stratified_return,omitempty"`
+
+	// RequestedEnd is the end asked for, set when ClampEnd moved End back to the last day with data
+	RequestedEnd *time.Time `json:"requested_end,omitempty"`
+	DurationMS   int64      `json:"duration_ms"`
+
+	// TradingDays is the number of NYSE sessions from Start through the last
+	// one the window has reached, which AnnualizedReturn is scaled by
+	TradingDays int `json:"trading_days"`
+
+	// Per-ticker fetch latency percentiles, including time spent retrying
+	LatencyP50MS float64 `json:"latency_p50_ms"`
+	LatencyP95MS float64 `json:"latency_p95_ms"`
+
+	RetriesUsed int `json:"retries_used"` // Retries spent from the run's budget
+	RetryBudget int `json:"retry_budget"`
+
+	// Failed tickers recovered by inline retries during the main pass, and
+	// by the retry pass over the main pass's remaining transient failures
+	RecoveredInline    int `json:"recovered_inline"`
+	RecoveredRetryPass int `json:"recovered_retry_pass"`
+	RetryPassTickers   int `json:"retry_pass_tickers"` // Failures queued for the retry pass
+
+	// Re-fetches of tickers that came back with no data, and how many of
+	// those tickers then got data
+	NoDataRetries   int `json:"no_data_retries"`
+	NoDataRecovered int `json:"no_data_recovered"`
+
+	// Top and bottom counts of the logged sector and ticker rankings
+	SectorSummaryCount int `json:"sector_summary_count"`
+	TickerSummaryCount int `json:"ticker_summary_count"`
+
+	// Skipped lists constituents whose symbols couldn't be normalized for Yahoo; they aren't counted in Tickers
+	Skipped []SkippedTicker `json:"skipped,omitempty"`
+
+	// Consolidated maps each share class left out of the run to the class kept for its company
+	Consolidated map[string]string `json:"consolidated,omitempty"`
+
+	// Unweighted lists the tickers a custom-weighted run's weights file
+	// doesn't cover; they count with zero weight
+	Unweighted []string `json:"unweighted,omitempty"`
+
+	// VolatilityStart is where Volatility's daily returns begin, when
+	// OMAHA_VOLATILITY_LOOKBACK_DAYS reaches before Start
+	VolatilityStart *time.Time `json:"volatility_start,omitempty"`
+
+	// Benchmark is the configured benchmark's return over the window, when enabled
+	Benchmark *Benchmark `json:"benchmark,omitempty"`
+
+	// Artifact export to S3-compatible storage, when configured
+	Exported    []string `json:"exported,omitempty"`     // Keys uploaded
+	ExportError string   `json:"export_error,omitempty"` // First upload failure; later artifacts are skipped
+
+	Errors         []TickerError `json:"-"` // Per-ticker failures, served at /api/errors
+	SuspectResults []Result      `json:"-"` // Results held out of the rankings, served at /api/suspect
+
+	// defaultOptions marks a run made with default options, the only kind the
+	// results cache serves for a window (see defaultRunOptions)
+	defaultOptions bool
+}
+
+// newRunID returns a sortable, unique identifier for a refresh run
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// FailureRate returns the fraction of tickers that failed to fetch
+func (rs RunSummary) FailureRate() float64 {
+	if rs.Tickers == 0 {
+		return 0
+	}
+	return float64(rs.Failed) / float64(rs.Tickers)
+}
+
+// logSectorTable logs the top n and bottom n sectors by average return, leaving
+// out the Unknown bucket. sectorReturns must already be sorted descending.
+func logSectorTable(sectorReturns []SectorReturn, n int) {
+	var sectors []SectorReturn
+	for _, sr := range sectorReturns {
+		if sr.Sector != unknownSector {
+			sectors = append(sectors, sr)
+		}
+	}
+	if n <= 0 || len(sectors) == 0 {
+		return
+	}
+	if n > len(sectors) {
+		n = len(sectors)
+	}
+
+	logRow := func(sr SectorReturn) {
+		log.Printf("%-30s %8s (%d tickers)",
+			sr.Sector+":", formatNumber("%.2f%%", sr.AvgReturn*100), sr.TickerCount)
+	}
+
+	log.Printf("\n🏆 Top %d Performing Sectors:", n)
+	for _, sr := range sectors[:n] {
+		logRow(sr)
+	}
+
+	// Avoid repeating rows when every sector fits in the top section
+	bottom := min(n, len(sectors)-n)
+	if bottom == 0 {
+		return
+	}
+	log.Printf("\n🐢 Bottom %d Performing Sectors:", bottom)
+	for _, sr := range sectors[len(sectors)-bottom:] {
+		logRow(sr)
+	}
+}
+
+// logTickerTable logs the top n and bottom n tickers by return.
+// results must already be sorted by return descending.
+func logTickerTable(results []Result, n int) {
+	if n <= 0 || len(results) == 0 {
+		return
+	}
+	if n > len(results) {
+		n = len(results)
+	}
+
+	logRow := func(r Result) {
+		log.Printf("%-8s %-30s %8s", r.Ticker, r.Sector, formatNumber("%.2f%%", r.Return*100))
+	}
+
+	log.Printf("\n📈 Top %d Tickers:", n)
+	for _, r := range results[:n] {
+		logRow(r)
+	}
+
+	// Avoid repeating rows when the whole list fits in the top section
+	bottom := n
+	if bottom > len(results)-n {
+		bottom = len(results) - n
+	}
+	if bottom == 0 {
+		return
+	}
+	log.Printf("\n📉 Bottom %d Tickers:", bottom)
+	for _, r := range results[len(results)-bottom:] {
+		logRow(r)
+	}
+}
+
+// fetchWorkers returns the size of the fetch worker pool: the number of CPU
+// cores * 2, but not more than maxWorkers to avoid rate limiting
+func fetchWorkers() int {
+	return min(runtime.NumCPU()*2, maxWorkers)
+}
+
+// getMTDResults fetches month-to-date returns for a specific month and year
+// If year and month are 0, it will use the previous month
+func getMTDResults(year int, month time.Month, day int, opts RunOptions) ([]Result, RunSummary, error) {
+	opts.Progress.begin()
+	defer opts.Progress.end()
+
+	clock := opts.Now
+	if clock == nil {
+		clock = time.Now
+	}
+	now := clock()
+
 	// If year and month are not provided, use previous month
 	if year == 0 || month == 0 {
-		lastMonth := time.Now().AddDate(0, -1, 0)
+		lastMonth := now.AddDate(0, -1, 0)
 		year, month, day = lastMonth.Year(), lastMonth.Month(), lastMonth.Day()
+	} else if day == 0 {
+		day = 1 // A window given by year and month starts on the 1st, as with ?year=&month=
 	}
 
 	start, end := getMonthRange(year, month, day)
+	if opts.TrailingDays > 0 {
+		today := now.In(cfg.Location)
+		end = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, cfg.Location)
+		start = tradingDaysBack(end, opts.TrailingDays)
+	}
+	if err := validateRange(start, end, now); err != nil {
+		return nil, RunSummary{}, err
+	}
+
+	if opts.TrailingDays > 0 {
+		fmt.Printf("📅 Fetching S&P 500 trailing %d-day returns (from %s to %s)...\n",
+			opts.TrailingDays,
+			start.Format("2006-01-02"),
+			end.Format("2006-01-02"))
+	} else {
+		fmt.Printf("📅 Fetching S&P 500 MTD returns for %s %d (from %s to %s)...\n",
+			month, year,
+			start.Format("2006-01-02"),
+			end.Format("2006-01-02"))
+	}
 
-	fmt.Printf("📅 Fetching S&P 500 MTD returns for %s %d (from %s to %s)...\n", 
-		month, year, 
-		start.Format("2006-01-02"), 
-		end.Format("2006-01-02"))
+	runStart := time.Now()
+	summary := RunSummary{RunID: newRunID(), Start: start, End: end, defaultOptions: defaultRunOptions(opts)}
 
-	tickers, sectors, err := getSP500Tickers()
+	var universe Universe
+	var err error
+	if opts.Fresh {
+		log.Println("🔄 Fresh run: bypassing ticker cache and checkpoint")
+	}
+	if opts.Source == nil {
+		summary.Index = opts.Index
+		if summary.Index == "" {
+			summary.Index = indexSP500
+		}
+	}
+	// Bound the scrape as a whole, so a hung connection can't stall the refresh
+	scrapeCtx, cancelScrape := context.WithTimeout(context.Background(), cfg.ScrapeTimeout)
+	defer cancelScrape()
+	switch {
+	case opts.Source != nil:
+		universe, err = opts.Source()
+	case opts.Index != "" && opts.Index != indexSP500:
+		universe, err = getIndexTickers(scrapeCtx, opts.Index)
+	default:
+		scrape := func() (Universe, error) { return getSP500Tickers(scrapeCtx) }
+		universe, summary.TickersCached, err = sp500Cache.get(cfg.TickerCacheTTL, scrape, opts.Fresh)
+		if summary.TickersCached {
+			log.Printf("📦 Using cached ticker list (%d tickers)\n", universe.Len())
+		}
+	}
+	if err == nil {
+		err = universe.aligned()
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrScrape, err)
+		notifyRun(cfg.WebhookURL, summary, err)
+		return nil, summary, err
+	}
+
+	// Rewrite scraped symbols into Yahoo's form, skipping any it wouldn't recognize
+	universe, summary.Skipped = normalizeUniverse(universe, cfg.SymbolSuffixes)
+	for _, sk := range summary.Skipped {
+		log.Printf("⏭️  Skipping %s: %s\n", sk.Ticker, sk.Reason)
+	}
+
+	// Count multi-class companies once
+	universe, summary.Consolidated = consolidateShareClasses(universe, cfg.ShareClasses)
+	for _, from := range slices.Sorted(maps.Keys(summary.Consolidated)) {
+		log.Printf("🔗 Consolidated %s into %s\n", from, summary.Consolidated[from])
+	}
+
+	// Index labels survive the filtering below, which rebuilds the universe
+	sources := universe.Sources
+
+	// Drop configured and ad-hoc exclusions before fetching
+	exclude := append(append([]string{}, cfg.Exclude...), opts.Exclude...)
+	universe, excluded := excludeTickers(universe, exclude)
+	if excluded > 0 {
+		log.Printf("🚫 Excluded %d tickers (%d remaining)\n", excluded, universe.Len())
+	}
+
+	// Truncate for quick development runs
+	limit := opts.Limit
+	if limit == 0 {
+		limit = cfg.TickerLimit
+	}
+	if limit > 0 && universe.Len() > limit {
+		log.Printf("✂️  Limiting run to the first %d of %d tickers\n", limit, universe.Len())
+		universe = Universe{
+			Tickers: universe.Tickers[:limit],
+			Sectors: universe.Sectors[:limit],
+			Names:   universe.Names[:limit],
+		}
+	}
+
+	// Try to recover sectors the scrape couldn't determine
+	unknownMode := cfg.UnknownSector
+	if !validUnknownSector(unknownMode) {
+		log.Printf("Warning: invalid OMAHA_UNKNOWN_SECTOR=%q, using %s", unknownMode, unknownKeep)
+		unknownMode = unknownKeep
+	}
+	if unknownMode == unknownBackfill {
+		if filled, n, err := backfillSectors(universe, cfg.Russell1000CSV); err != nil {
+			log.Printf("Warning: skipping sector backfill: %v", err)
+		} else {
+			universe = filled
+			log.Printf("🧩 Backfilled %d unknown sectors\n", n)
+		}
+	}
+
+	// One seeded source drives the run's randomness, so a run can be replayed
+	rng, seed := newRunRand(opts.Seed)
+	summary.Seed = seed
+
+	// Draw a sample for a quick estimate, remembering the full sector sizes
+	var population map[string]int
+	if opts.Sample > 0 && opts.Sample < universe.Len() {
+		population = make(map[string]int)
+		for _, sector := range universe.Sectors {
+			population[sector]++
+		}
+		summary.Sampled, summary.Stratified, summary.Population = true, opts.Stratify, universe.Len()
+		universe = sampleUniverse(universe, opts.Sample, opts.Stratify, rng)
+		log.Printf("🎲 SAMPLED run: %d of %d tickers (stratified: %t, seed: %d); averages are estimates\n",
+			universe.Len(), summary.Population, opts.Stratify, seed)
+	}
+	// Checkpoints hold full runs, so samples neither resume from nor write them
+	useCheckpoint := !opts.Fresh && !summary.Sampled
+
+	summary.Tickers = universe.Len()
+	opts.Progress.setTotal(summary.Tickers)
+
+	// Resolve the optional metrics before any result is collected
+	metricList := opts.Metrics
+	if metricList == "" {
+		metricList = cfg.Metrics
+	}
+	metrics, err := parseMetrics(metricList)
 	if err != nil {
-		log.Fatalf("Failed to get tickers: %v", err)
+		log.Printf("Warning: invalid metrics %q (%v), computing all", metricList, err)
+		metrics = allMetrics
+	}
+	summary.Metrics = metrics.names()
+
+	// Load the portfolio weights for a custom-weighted run; tickers it doesn't
+	// list weigh nothing
+	weighting := opts.Weighting
+	if weighting == "" {
+		weighting = cfg.Weighting
+	}
+	if !validWeighting(weighting) {
+		log.Printf("Warning: invalid weighting %q, using %s", weighting, weightingEqual)
+		weighting = weightingEqual
+	}
+	var weights map[string]float64
+	if weighting == weightingCustom {
+		if cfg.WeightsCSV == "" {
+			log.Printf("Warning: custom weighting needs OMAHA_WEIGHTS_CSV, using %s", weightingEqual)
+		} else if weights, err = loadWeightsCSV(cfg.WeightsCSV); err != nil {
+			log.Printf("Warning: using %s weighting: %v", weightingEqual, err)
+		}
+		if missing := missingWeights(universe, weights); weights != nil && len(missing) > 0 {
+			summary.Unweighted = missing
+			shown := missing[:min(len(missing), 10)]
+			log.Printf("⚖️  %d tickers have no weight and count as 0: %s\n", len(missing), strings.Join(shown, ", "))
+		}
+	}
+	summary.Weighting = weightingEqual
+	if weights != nil {
+		summary.Weighting = weightingCustom
+	}
+	weigh := func(r *Result) {
+		if weights != nil {
+			w := weights[r.Ticker]
+			r.Weight = &w
+		}
 	}
 
 	// Create a map to store sector data
-	sectorData := make(map[string]struct {
-		totalReturn float64
-		count       int
-	})
+	sectorData := make(map[string]returnSum)
+	addToSector := func(r Result) {
+		// Skip NaN returns so they can't skew the average
+		if math.IsNaN(r.Return) || (unknownMode == unknownDrop && r.Sector == unknownSector) {
+			return
+		}
+		sd := sectorData[r.Sector]
+		sd.addResult(r)
+		sectorData[r.Sector] = sd
+	}
+
+	returnType := returnSimple
+	if opts.ReturnType == returnLog {
+		returnType = returnLog
+	}
+	summary.ReturnType = returnType
+	returnBasis := basisClose
+	if opts.ReturnBasis == basisVWAP {
+		returnBasis = basisVWAP
+	}
+	summary.ReturnBasis = returnBasis
+	baseline := opts.Baseline
+	if baseline == "" {
+		baseline = cfg.Baseline
+	}
+	if baseline == "" {
+		baseline = baselineFirstInWindow
+	}
+	fopts := FetchOptions{
+		PeriodStarts: periodStarts(opts.Periods, start),
+		ReturnType:   returnType,
+		ReturnBasis:  returnBasis,
+		PaddingDays:  cfg.FetchPaddingDays,
+		Baseline:     baseline,
+		Metrics:      metrics,
+		ForwardFill:  cfg.ForwardFill,
+	}
+	if opts.ForwardFill != nil {
+		fopts.ForwardFill = *opts.ForwardFill
+	}
+
+	// Trace every ticker fetch back to this run
+	ctx := withTrace(context.Background(), summary.RunID)
+
+	// The last bar should land on the last trading day the window has reached
+	expectedLast := lastTradingDay(end, now)
+	expectedBars := tradingDaysBetween(start, expectedLast)
+
+	// Measure volatility over a trailing lookback when it reaches before the window
+	if n := cfg.VolatilityLookbackDays; n > 0 && metrics.Volatility {
+		if vs := tradingDaysBack(expectedLast, n); vs.Before(start) {
+			fopts.VolatilityStart = vs
+			summary.VolatilityStart = &vs
+		}
+	}
+
+	minVolume := opts.MinVolume
+	if minVolume == 0 {
+		minVolume = cfg.MinAvgVolume
+	}
+	minPrice := opts.MinPrice
+	if minPrice == 0 {
+		minPrice = cfg.MinPrice
+	}
+
+	// Convert returns into the target currency, fetching each pair's rates once
+	target := opts.TargetCurrency
+	if target == "" {
+		target = cfg.TargetCurrency
+	}
+	var fx *fxCache
+	switch target = strings.ToUpper(target); {
+	case target == "":
+	case !validCurrency(target):
+		log.Printf("Warning: invalid target currency %q, skipping conversions", target)
+	default:
+		fx = newFXCache(yahooFX, target, start, end)
+		summary.TargetCurrency = target
+	}
+
+	// Resume from a checkpoint of a previous, interrupted run over the same
+	// window and universe with the same fetch options and filters
+	filters := resultFilters{MinPrice: minPrice, MinVolume: minVolume, StaleMaxDays: cfg.StaleMaxDays, MaxAbsReturn: cfg.MaxAbsReturn}
+	cpKey := checkpointKey(summary.Index, opts.FetchMode, summary.TargetCurrency, fopts, filters)
+	var validResults []Result
+	var checkpointed []Result
+	if useCheckpoint {
+		checkpointed, err = loadCheckpoint(start, end, cpKey)
+		if err != nil {
+			log.Printf("Warning: ignoring checkpoint: %v", err)
+		}
+	}
+	if len(checkpointed) > 0 {
+		wanted := make(map[string]bool, universe.Len())
+		for _, ticker := range universe.Tickers {
+			wanted[ticker] = true
+		}
+		done := make(map[string]bool, len(checkpointed))
+		for _, r := range checkpointed {
+			if !wanted[r.Ticker] || done[r.Ticker] || math.IsNaN(r.Return) {
+				continue
+			}
+			done[r.Ticker] = true
+			metrics.clear(&r)
+			weigh(&r)
+			validResults = append(validResults, r)
+			addToSector(r)
+		}
+
+		var pending Universe
+		for i, ticker := range universe.Tickers {
+			if done[ticker] {
+				continue
+			}
+			pending.add(ticker, universe.Sectors[i], universe.Names[i])
+		}
+		universe = pending
+		opts.Progress.advance(len(done))
+		log.Printf("♻️  Resumed %d tickers from checkpoint (%d remaining)\n", len(done), universe.Len())
+	}
 
 	// Process tickers in parallel
 	type jobResult struct {
-		ticker string
-		sector string
-		result MTDResult
-		err    error
+		ticker  string
+		sector  string
+		name    string
+		result  MTDResult
+		err     error
+		latency time.Duration
+
+		attempts  int  // Fetches made, including inline retries
+		retryPass bool // Re-fetched by the retry pass after the main pass
 	}
 
-	// Calculate number of workers (use number of CPU cores * 2, but not more than maxWorkers to avoid rate limiting)
-	workers := runtime.NumCPU() * 2
-	if workers > maxWorkers {
-		workers = maxWorkers
+	workers := fetchWorkers()
+
+	// Fetch in the requested order so streamed results arrive predictably
+	fetchOrder := opts.FetchOrder
+	if fetchOrder == "" {
+		fetchOrder = cfg.FetchOrder
 	}
+	if !validFetchOrder(fetchOrder) {
+		log.Printf("Warning: invalid fetch order %q, using %s", fetchOrder, fetchOrderScrape)
+		fetchOrder = ""
+	}
+	summary.FetchOrder = orDefault(fetchOrder, fetchOrderScrape)
+	universe = orderUniverse(universe, fetchOrder)
 
 	// Process tickers in parallel using a worker pool
-	numTickers := len(tickers)
+	numTickers := universe.Len()
 	jobs := make(chan jobResult, numTickers)
 	results := make(chan jobResult, numTickers)
 
+	// Serve short windows from batched quotes where possible
+	summary.FetchMode = fetchChart
+	var quoted map[string]MTDResult
+	if opts.FetchMode == fetchQuote {
+		if reason := quoteIneligible(start, expectedLast, fopts); reason != "" {
+			log.Printf("📈 Using charts instead of quotes: %s\n", reason)
+		} else {
+			summary.FetchMode = fetchQuote
+			quoted = fetchQuotes(ctx, universe.Tickers, fopts)
+			log.Printf("📈 Quoted %d of %d tickers; charting the rest\n", len(quoted), numTickers)
+		}
+	}
+
+	// fetchOnce recovers a panic in a single ticker's fetch as that ticker's error
+	fetchOnce := func(ticker string) (result MTDResult, err error) {
+		defer recoverAsError(&err, ticker)
+		if res, ok := quoted[ticker]; ok {
+			result = res
+		} else if result, err = getMTDReturn(withSpan(ctx, ticker), ticker, start, end, fopts); err != nil {
+			return result, err
+		}
+		if fx != nil {
+			if err := fx.convert(ctx, &result, returnType); err != nil && !errors.Is(err, errFXUnavailable) {
+				log.Printf("Warning: skipping %s conversion for %s: %v", target, ticker, err)
+			}
+		}
+		return result, nil
+	}
+
+	// fetchData re-fetches a ticker that came back with no data, up to
+	// OMAHA_NO_DATA_RETRIES times. Yahoo sometimes returns an empty chart
+	// transiently, but these aren't failures, so they don't spend the retry budget.
+	var noDataRetries, noDataRecovered atomic.Int64
+	fetchData := func(ticker string) (MTDResult, error) {
+		result, err := fetchOnce(ticker)
+		for retry := 0; retry < cfg.NoDataRetries && errors.Is(err, ErrNoData); retry++ {
+			time.Sleep(cfg.NoDataRetryDelay)
+			noDataRetries.Add(1)
+			if result, err = fetchOnce(ticker); err == nil {
+				noDataRecovered.Add(1)
+				log.Printf("🔁 Got data for %s on no-data retry %d\n", ticker, retry+1)
+			}
+		}
+		return result, err
+	}
+
+	// fetch retries transient failures while the run's retry budget lasts.
+	// With a retry pass, failures wait for it instead of holding a worker in
+	// backoff, so nothing is retried inline.
+	budget := &retryBudget{limit: int64(cfg.RetryBudget)}
+	jitter := &retryJitter{rng: rng}
+	inlineRetries := cfg.FetchRetries
+	if cfg.RetryPassAttempts > 0 {
+		inlineRetries = 0
+	}
+	fetch := func(ticker string) (MTDResult, int, error) {
+		for attempt := 0; ; attempt++ {
+			result, err := fetchData(ticker)
+			if err == nil || attempt >= inlineRetries || !retryable(err) || !budget.take() {
+				return result, attempt + 1, err
+			}
+			time.Sleep(jitter.backoff(attempt))
+		}
+	}
+
+	// runRetryPass re-fetches the main pass's transient failures with fewer
+	// workers, after a pause, so throttled tickers get a second chance
+	// without holding main-pass workers in backoff. Each attempt spends one
+	// retry from the run's budget; once it is exhausted the failures stand.
+	runRetryPass := func(queue []jobResult) {
+		retryJobs := make(chan jobResult, len(queue))
+		for _, j := range queue {
+			j.retryPass = true
+			retryJobs <- j
+		}
+		close(retryJobs)
+		for w := 0; w < max(cfg.RetryPassWorkers, 1); w++ {
+			go func() {
+				for j := range retryJobs {
+					fetchStart := time.Now()
+					for attempt := 0; attempt < cfg.RetryPassAttempts && budget.take(); attempt++ {
+						time.Sleep(jitter.backoff(attempt))
+						if j.result, j.err = fetchData(j.ticker); j.err == nil || !retryable(j.err) {
+							break
+						}
+					}
+					j.latency = time.Since(fetchStart)
+					results <- j
+				}
+			}()
+		}
+	}
+
 	// Start workers
 	for w := 0; w < workers; w++ {
 		go func() {
 			for j := range jobs {
-				result, err := getMTDReturn(j.ticker, start, end)
-				if err != nil {
-					results <- jobResult{ticker: j.ticker, sector: j.sector, err: err}
-					continue
-				}
-				results <- jobResult{ticker: j.ticker, sector: j.sector, result: result}
+				fetchStart := time.Now()
+				j.result, j.attempts, j.err = fetch(j.ticker)
+				j.latency = time.Since(fetchStart)
+				results <- j
 			}
 		}()
 	}
 
 	// Send jobs
 	go func() {
-		for i, ticker := range tickers {
-			sector := "Unknown"
-			if i < len(sectors) {
-				sector = sectors[i]
-			}
-			jobs <- jobResult{ticker: ticker, sector: sector}
+		for i, ticker := range universe.Tickers {
+			jobs <- jobResult{ticker: ticker, sector: universe.Sectors[i], name: universe.Names[i]}
 		}
 		close(jobs)
 	}()
 
 	// Collect results
-	var validResults []Result
-	var errs []error
+	var errs []TickerError
+	var suspect []Result
+	var latencies []time.Duration
+	var latestBar time.Time
+	sinceCheckpoint := 0
+	var retryQueue []jobResult
+	inRetryPass := false
 
-	for i := 0; i < numTickers; i++ {
+	for pending := numTickers; pending > 0 || len(retryQueue) > 0; pending-- {
+		if pending == 0 {
+			log.Printf("🔁 Retry pass over %d failed tickers\n", len(retryQueue))
+			summary.RetryPassTickers = len(retryQueue)
+			runRetryPass(retryQueue)
+			pending, retryQueue, inRetryPass = len(retryQueue), nil, true
+		}
 		res := <-results
+		// Transient failures wait for the retry pass rather than counting as errors now
+		if res.err != nil && !inRetryPass && cfg.RetryPassAttempts > 0 && retryable(res.err) {
+			retryQueue = append(retryQueue, res)
+			continue
+		}
+		latencies = append(latencies, res.latency)
+		opts.Progress.complete()
 		if res.err != nil {
-			errs = append(errs, fmt.Errorf("%s: %v", res.ticker, res.err))
+			errs = append(errs, newTickerError(res.ticker, res.err))
+			continue
+		}
+		if math.IsNaN(res.result.Return) {
+			errs = append(errs, newTickerError(res.ticker, fmt.Errorf("%w: NaN", ErrInvalidReturn)))
+			continue
+		}
+		lag := staleDays(res.result.LastBarTime, expectedLast)
+		if cfg.StaleMaxDays > 0 && lag > cfg.StaleMaxDays {
+			errs = append(errs, newTickerError(res.ticker, fmt.Errorf("%w: last bar %d days before %s",
+				ErrStale, lag, expectedLast.Format("2006-01-02"))))
+			continue
+		}
+		if minPrice > 0 && res.result.LastClose.LessThan(decimal.NewFromFloat(minPrice)) {
+			errs = append(errs, newTickerError(res.ticker, fmt.Errorf("%w: last close %s below %s",
+				ErrBelowMinPrice, res.result.LastClose, decimal.NewFromFloat(minPrice))))
+			continue
+		}
+		if minVolume > 0 && res.result.AvgVolume < minVolume {
+			errs = append(errs, newTickerError(res.ticker, fmt.Errorf("%w: average volume %.0f below %.0f",
+				ErrIlliquid, res.result.AvgVolume, minVolume)))
 			continue
 		}
 
 		result := Result{
 			Ticker:     res.ticker,
+			Name:       res.name,
 			Sector:     res.sector,
 			Return:     res.result.Return,
 			BarCount:   res.result.BarCount,
 			FirstClose: res.result.FirstClose.String(),
 			LastClose:  res.result.LastClose.String(),
+			AvgVolume:  res.result.AvgVolume,
+			Indexes:    sources[res.ticker],
+
+			PriceChange: res.result.PriceChange.String(),
+			UpDays:      res.result.UpDays,
+			DownDays:    res.result.DownDays,
+			Volatility:  res.result.Volatility,
+			MaxDrawdown: res.result.MaxDrawdown,
+
+			CurrencyReturn: res.result.CurrencyReturn,
+
+			Baseline:     baseline,
+			BaselineDate: res.result.BaselineDate.Format("2006-01-02"),
+
+			ExpectedBars: expectedBars,
+			Complete:     res.result.BarCount >= expectedBars,
+		}
+		if lag > cfg.StaleDays {
+			result.Stale, result.StaleDays = true, lag
+		}
+		if fx != nil {
+			result.Currency = res.result.Currency
+		}
+		setPeriodReturns(&result, res.result.PeriodReturns)
+
+		// Implausible returns are usually bad bars; hold them for review instead of ranking them
+		if cfg.MaxAbsReturn > 0 && math.Abs(result.Return) > cfg.MaxAbsReturn {
+			log.Printf("⚠️  Holding %s for review: return %s exceeds ±%s",
+				res.ticker, formatNumber("%.2f%%", result.Return*100), formatNumber("%.0f%%", cfg.MaxAbsReturn*100))
+			suspect = append(suspect, result)
+			continue
 		}
+		weigh(&result)
 		validResults = append(validResults, result)
+		addToSector(result)
+		switch {
+		case res.retryPass:
+			summary.RecoveredRetryPass++
+		case res.attempts > 1:
+			summary.RecoveredInline++
+		}
+		if res.result.LastBarTime.After(latestBar) {
+			latestBar = res.result.LastBarTime
+		}
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
 
-		// Update sector data
-		sd := sectorData[res.sector]
-		sd.totalReturn += result.Return
-		sd.count++
-		sectorData[res.sector] = sd
+		// Periodically checkpoint so a crash doesn't lose fetched data.
+		// Fresh and sampled runs leave any existing checkpoint untouched.
+		if sinceCheckpoint++; useCheckpoint && sinceCheckpoint >= checkpointInterval {
+			if err := saveCheckpoint(start, end, cpKey, validResults); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			sinceCheckpoint = 0
+		}
+	}
+
+	if summary.RetryPassTickers > 0 {
+		log.Printf("🔁 Retry pass recovered %d of %d tickers\n", summary.RecoveredRetryPass, summary.RetryPassTickers)
 	}
 
 	// Log any errors
@@ -373,6 +1987,46 @@ func getMTDResults(year int, month time.Month, day int) ([]Result, error) {
 		log.Printf("Completed with %d errors during processing\n", len(errs))
 	}
 
+	// Report the window as ending on the last day any ticker has data
+	windowEnd := end
+	if opts.ClampEnd && !latestBar.IsZero() {
+		lb := latestBar.In(cfg.Location)
+		if lastDay := time.Date(lb.Year(), lb.Month(), lb.Day(), 0, 0, 0, 0, cfg.Location); lastDay.Before(end) {
+			log.Printf("📌 Clamping window end from %s to %s, the last day with data\n",
+				end.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+			windowEnd = lastDay
+			summary.End, summary.RequestedEnd = lastDay, &end
+		}
+	}
+
+	// Subtract the prorated risk-free rate when excess returns are enabled
+	if annual, ok, err := riskFreeRate(cfg.RiskFreeRate, start, windowEnd); err != nil {
+		log.Printf("Warning: skipping excess returns: %v", err)
+	} else if ok {
+		rf := prorateRate(annual, start, windowEnd)
+		log.Printf("📉 Risk-free rate %.2f%% annualized (%.4f%% over window)\n", annual*100, rf*100)
+		for i := range validResults {
+			excess := validResults[i].Return - rf
+			validResults[i].ExcessReturn = &excess
+		}
+	}
+
+	// Count the sessions the window has reached, so weekends and holidays
+	// don't stretch annualization
+	summary.TradingDays = tradingDaysBetween(start, lastTradingDay(windowEnd, now))
+
+	// Scale returns to a year so windows of different lengths compare
+	if opts.Annualize && summary.TradingDays > 0 {
+		for i := range validResults {
+			annual := annualizeReturn(validResults[i].Return, summary.TradingDays, returnType)
+			if limit := cfg.MaxAnnualizedReturn; limit > 0 && math.Abs(annual) > limit {
+				annual = math.Copysign(limit, annual)
+				validResults[i].AnnualizedCapped = true
+			}
+			validResults[i].AnnualizedReturn = &annual
+		}
+	}
+
 	// Sort valid results by return descending
 	sort.Slice(validResults, func(i, j int) bool {
 		return validResults[i].Return > validResults[j].Return
@@ -381,10 +2035,15 @@ func getMTDResults(year int, month time.Month, day int) ([]Result, error) {
 	// Convert sector data to slice for sorting
 	var sectorReturns []SectorReturn
 	for sector, data := range sectorData {
+		if data.count == 0 {
+			continue
+		}
 		sectorReturns = append(sectorReturns, SectorReturn{
-			Sector:      sector,
-			AvgReturn:   data.totalReturn / float64(data.count),
-			TickerCount: data.count,
+			Sector:              sector,
+			AvgReturn:           data.mean(),
+			GeometricMeanReturn: data.geometricMean(),
+			TrimmedReturn:       data.trimmedMean(cfg.TrimFraction),
+			TickerCount:         data.count,
 		})
 	}
 
@@ -393,26 +2052,185 @@ func getMTDResults(year int, month time.Month, day int) ([]Result, error) {
 		return sectorReturns[i].AvgReturn > sectorReturns[j].AvgReturn
 	})
 
+	// Second pass: compare each ticker with its sector's average
+	sectorAvg := make(map[string]float64, len(sectorReturns))
+	for _, sr := range sectorReturns {
+		sectorAvg[sr.Sector] = sr.AvgReturn
+	}
+	for i := range validResults {
+		avg, ok := sectorAvg[validResults[i].Sector]
+		if !ok || validResults[i].Sector == unknownSector || !metrics.RelativeToSector {
+			continue
+		}
+		relative := validResults[i].Return - avg
+		validResults[i].RelativeToSector = &relative
+	}
+
+	// Fetch the benchmark over the same window, outside the results so it can't skew them
+	if cfg.Benchmark != "" {
+		if res, _, err := fetch(cfg.Benchmark); err != nil {
+			log.Printf("Warning: skipping benchmark %s: %v", cfg.Benchmark, err)
+		} else {
+			summary.Benchmark = &Benchmark{Ticker: cfg.Benchmark, Return: res.Return}
+			log.Printf("📏 Benchmark %s: %s\n", cfg.Benchmark, formatNumber("%.2f%%", res.Return*100))
+		}
+	}
+
 	// Write results to CSV
-	outputFile := "sp500_mtd_returns.csv"
-	if err := writeResultsToCSV(validResults, sectorReturns, outputFile); err != nil {
+	units := opts.Units
+	if units == "" {
+		units = cfg.ReturnUnits
+	}
+	csvOpts := CSVOptions{Units: normalizeUnits(units), SortBy: cfg.CSVSort}
+	if cfg.CSVBenchmarkRow {
+		csvOpts.Benchmark = summary.Benchmark
+	}
+	csvPath := ""
+	outputMu.Lock()
+	if err := writeResultsToCSV(validResults, sectorReturns, outputFile, csvOpts); err != nil {
 		log.Printf("Warning: Failed to write CSV: %v", err)
 	} else {
 		log.Printf("✅ Saved results to %s\n", outputFile)
+		csvPath = outputFile
+		if cfg.CSVPerSector {
+			if err := writeSectorCSVs(validResults, sectorReturns, filepath.Dir(outputFile), csvOpts); err != nil {
+				log.Printf("Warning: Failed to write sector CSVs: %v", err)
+			} else {
+				log.Printf("✅ Saved %d sector CSVs\n", len(sectorReturns))
+			}
+		}
+		if cfg.CSVPivot {
+			if err := writePivotToCSV(validResults, sectorReturns, outputPivot, csvOpts); err != nil {
+				log.Printf("Warning: Failed to write pivot CSV: %v", err)
+			} else {
+				log.Printf("✅ Saved sector pivot to %s\n", outputPivot)
+			}
+		}
+		if cfg.XLSX {
+			if err := writeResultsToXLSX(validResults, sectorReturns, outputXLSXFile, csvOpts); err != nil {
+				log.Printf("Warning: Failed to write workbook: %v", err)
+			} else {
+				log.Printf("✅ Saved results to %s\n", outputXLSXFile)
+			}
+		}
+
+		logSectorTable(sectorReturns, cfg.SectorSummaryCount)
+	}
+	outputMu.Unlock()
+
+	logTickerTable(validResults, cfg.SummaryCount)
+	summary.SectorSummaryCount, summary.TickerSummaryCount = cfg.SectorSummaryCount, cfg.SummaryCount
 
-		// Log top 5 sectors
-		log.Println("\n🏆 Top 5 Performing Sectors:")
-		for i := 0; i < 5 && i < len(sectorReturns); i++ {
-			sr := sectorReturns[i]
-			log.Printf("%-30s %6.2f%% (%d tickers)", 
-				sr.Sector + ":", sr.AvgReturn*100, sr.TickerCount)
+	if useCheckpoint {
+		removeCheckpoint(start, end, cpKey)
+	}
+
+	summary.Fetched = len(validResults)
+	summary.Failed = len(errs)
+	summary.Errors = errs
+	summary.Suspect = len(suspect)
+	summary.SuspectResults = suspect
+	summary.DurationMS = time.Since(runStart).Milliseconds()
+	summary.RetryBudget, summary.RetriesUsed = cfg.RetryBudget, int(budget.used.Load())
+	if summary.RetriesUsed > 0 {
+		log.Printf("🔁 Used %d of %d retries\n", summary.RetriesUsed, summary.RetryBudget)
+	}
+	summary.NoDataRetries, summary.NoDataRecovered = int(noDataRetries.Load()), int(noDataRecovered.Load())
+	if summary.NoDataRetries > 0 {
+		log.Printf("🔁 %d no-data retries recovered %d tickers\n", summary.NoDataRetries, summary.NoDataRecovered)
+	}
+	summary.LatencyP50MS = float64(percentile(latencies, 50)) / float64(time.Millisecond)
+	summary.LatencyP95MS = float64(percentile(latencies, 95)) / float64(time.Millisecond)
+	log.Printf("⏱️  Fetch latency p50 %.0fms, p95 %.0fms\n", summary.LatencyP50MS, summary.LatencyP95MS)
+	if len(validResults) > 0 {
+		var index returnSum
+		for _, r := range validResults {
+			index.addResult(r)
+		}
+		summary.IndexReturn = index.mean()
+		summary.TrimmedReturn = index.trimmedMean(cfg.TrimFraction)
+		if summary.Stratified {
+			if r := weightedIndexReturn(validResults, population); !math.IsNaN(r) {
+				summary.StratifiedReturn = &r
+			}
 		}
 	}
 
-	return validResults, nil
+	if exporter != nil {
+		exportRun(exporter, &summary, validResults, csvPath)
+	}
+
+	notifyRun(cfg.WebhookURL, summary, nil)
+	return validResults, summary, nil
 }
 
 func main() {
+	mode := flag.String("mode", "server", "Run mode: server or cli")
+	year := flag.Int("year", 0, "Target year for cli mode (default: previous month)")
+	month := flag.Int("month", 0, "Target month (1-12) for cli mode")
+	day := flag.Int("day", 1, "Target day (1-31) the cli window starts on")
+	source := flag.String("source", "wikipedia", "Ticker source for cli mode: wikipedia or stdin")
+	index := flag.String("index", indexSP500, "Index universe for cli mode: "+indexUsage)
+	asOf := flag.String("asof", "", "Run cli mode as of this date (YYYY-MM-DD) instead of today")
+	snapshot := flag.String("baseline", "", "Compare cli results against an earlier run's JSON or CSV file")
+	report := flag.String("report", "", "Write a standalone HTML report of the cli results to this path")
+	sample := flag.Int("sample", 0, "Fetch only a random sample of N tickers in cli mode (0 fetches all)")
+	stratify := flag.Bool("stratify", false, "Stratify -sample by sector")
+	seed := flag.Uint64("seed", 0, "Seed for -sample and retry jitter, to reproduce an earlier run (0 seeds from the clock)")
+	targetCurrency := flag.String("target-currency", "", "ISO 4217 currency to also convert returns into, e.g. EUR (default OMAHA_TARGET_CURRENCY)")
+	metrics := flag.String("metrics", "", "Optional metrics to compute: volatility, max_drawdown, relative_to_sector, or none (default OMAHA_METRICS, or all)")
+	weighting := flag.String("weighting", "", "Weighting of sector and index averages: equal or custom (default OMAHA_WEIGHTING)")
+	annualize := flag.Bool("annualize", false, "Add annualized returns to the cli results")
+	sectorCount := flag.Int("sector-count", cfg.SectorSummaryCount, "Number of top and bottom sectors logged at the end of a run")
+	tickerCount := flag.Int("ticker-count", cfg.SummaryCount, "Number of top and bottom tickers logged at the end of a run")
+	perSector := flag.Bool("per-sector", cfg.CSVPerSector, "Also write one CSV per sector next to the combined CSV")
+	xlsx := flag.Bool("xlsx", cfg.XLSX, "Also write the results as an Excel workbook next to the CSV")
+	pivot := flag.Bool("pivot", cfg.CSVPivot, "Also write a sector-by-metric pivot CSV next to the CSV")
+	clampEnd := flag.Bool("clamp-end", false, "Report the cli window as ending on the last day with data")
+	flag.Parse()
+	cfg.SectorSummaryCount, cfg.SummaryCount = *sectorCount, *tickerCount
+	cfg.CSVPerSector, cfg.XLSX, cfg.CSVPivot = *perSector, *xlsx, *pivot
+
+	configureFinanceClient(cfg)
+
+	if *mode == "cli" {
+		if !validIndex(*index) {
+			log.Printf("Unknown index %q (expected %s)", *index, indexUsage)
+			os.Exit(exitUsage)
+		}
+		daySet := false
+		flag.Visit(func(f *flag.Flag) { daySet = daySet || f.Name == "day" })
+		windowYear, windowMonth, windowDay, err := cliWindow(*year, *month, *day, daySet)
+		if err != nil {
+			log.Printf("Invalid window: %v", err)
+			os.Exit(exitUsage)
+		}
+		opts := RunOptions{Index: *index, Sample: *sample, Stratify: *stratify, Seed: *seed, Weighting: *weighting, Metrics: *metrics, TargetCurrency: *targetCurrency, ClampEnd: *clampEnd, Annualize: *annualize}
+		if *asOf != "" {
+			clock, err := fixedClock(*asOf)
+			if err != nil {
+				log.Printf("Invalid -asof: %v", err)
+				os.Exit(exitUsage)
+			}
+			opts.Now = clock
+		}
+		switch *source {
+		case "wikipedia":
+		case "stdin":
+			opts.Source = func() (Universe, error) { return readTickers(os.Stdin) }
+		default:
+			log.Printf("Unknown source %q (expected wikipedia or stdin)", *source)
+			os.Exit(exitUsage)
+		}
+		code := runCLI(windowYear, windowMonth, windowDay, opts, CLIOptions{Snapshot: *snapshot, Report: *report})
+		notifications.Wait() // Deliver the run's webhook before exiting
+		os.Exit(code)
+	}
+	if *mode != "server" {
+		log.Printf("Unknown mode %q (expected server or cli)", *mode)
+		os.Exit(exitUsage)
+	}
+
 	// Initialize the server
 	server := NewServer()
 
@@ -422,6 +2240,13 @@ func main() {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
+	if cfg.RPCAddr != "off" {
+		go func() {
+			if err := server.StartRPC(cfg.RPCAddr); err != nil {
+				log.Fatalf("JSON-RPC server error: %v", err)
+			}
+		}()
+	}
 
 	log.Println("🚀 Server started. Use the refresh button in the UI to load data.")
 
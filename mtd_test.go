@@ -0,0 +1,927 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+	"github.com/shopspring/decimal"
+)
+
+func TestCalculateSectorReturns(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name    string
+		results []Result
+		want    map[string]float64 // AvgReturn by sector
+	}{
+		{name: "no results", results: nil, want: map[string]float64{}},
+		{
+			name: "averages per sector",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1},
+				{Ticker: "B", Sector: "Tech", Return: 0.3},
+				{Ticker: "C", Sector: "Energy", Return: -0.2},
+			},
+			want: map[string]float64{"Tech": 0.2, "Energy": -0.2},
+		},
+		{
+			name: "NaN returns skipped",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1},
+				{Ticker: "B", Sector: "Tech", Return: nan},
+			},
+			want: map[string]float64{"Tech": 0.1},
+		},
+		{
+			name: "all-NaN sector omitted",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1},
+				{Ticker: "B", Sector: "Energy", Return: nan},
+			},
+			want: map[string]float64{"Tech": 0.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateSectorReturns(tt.results)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d sectors (%+v), want %d", len(got), got, len(tt.want))
+			}
+			for _, sr := range got {
+				want, ok := tt.want[sr.Sector]
+				if !ok || math.Abs(sr.AvgReturn-want) > 1e-12 || math.IsNaN(sr.AvgReturn) {
+					t.Errorf("%s: AvgReturn %v, want %v", sr.Sector, sr.AvgReturn, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFailedFetchesStayOutOfResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		bars     []fakeBar
+		queue    []error
+		category string
+	}{
+		{name: "empty chart", queue: []error{errEmptyChart, errEmptyChart}, category: "no_data"},
+		{
+			name:     "non-positive closes",
+			bars:     []fakeBar{{date: "2025-09-02", close: -1}, {date: "2025-09-03", close: 0}},
+			category: "bad_data",
+		},
+		{name: "unknown symbol", category: "symbol_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["GOOD"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+			if tt.bars != nil || tt.queue != nil {
+				f.charts["BAD"] = tt.bars
+			}
+			f.queue("BAD", tt.queue...)
+
+			results, summary, err := runFake(t, []string{"GOOD", "BAD"}, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Ticker != "GOOD" {
+				t.Fatalf("results = %+v, want only GOOD", results)
+			}
+			if len(summary.Errors) != 1 || summary.Errors[0].Ticker != "BAD" || summary.Errors[0].Category != tt.category {
+				t.Errorf("errors = %+v, want BAD as %s", summary.Errors, tt.category)
+			}
+			if math.IsNaN(summary.IndexReturn) || math.IsNaN(results[0].Return) {
+				t.Errorf("NaN leaked: index %v, GOOD %v", summary.IndexReturn, results[0].Return)
+			}
+		})
+	}
+}
+
+func TestValidateRange(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, cfg.Location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	now := day("2025-10-15")
+	tests := []struct {
+		name       string
+		start, end string
+		wantErr    bool
+	}{
+		{name: "past month", start: "2025-09-01", end: "2025-09-30"},
+		{name: "current month", start: "2025-10-01", end: "2025-10-31"},
+		{name: "single day", start: "2025-09-15", end: "2025-09-15"},
+		{name: "starts today", start: "2025-10-15", end: "2025-10-20"},
+		{name: "reversed", start: "2025-09-30", end: "2025-09-01", wantErr: true},
+		{name: "in the future", start: "2025-11-01", end: "2025-11-30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRange(day(tt.start), day(tt.end), now)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidRange)) {
+				t.Errorf("validateRange = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreviousMonthFromClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{name: "mid month", now: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC), wantStart: "2025-09-15", wantEnd: "2025-10-14"},
+		{name: "first of month", now: time.Date(2025, 10, 1, 18, 0, 0, 0, time.UTC), wantStart: "2025-09-01", wantEnd: "2025-09-30"},
+		{name: "across a year", now: time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC), wantStart: "2025-12-01", wantEnd: "2025-12-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeRun(t)
+			opts := RunOptions{
+				Source: func() (Universe, error) { return Universe{}, nil },
+				Now:    func() time.Time { return tt.now },
+			}
+			_, summary, err := getMTDResults(0, 0, 0, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary.Start.Format("2006-01-02"); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := summary.End.Format("2006-01-02"); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestFixedClock(t *testing.T) {
+	tests := []struct {
+		date    string
+		want    string
+		wantErr bool
+	}{
+		{date: "2025-09-15", want: "2025-09-15T23:59:59"},
+		{date: "2024-02-29", want: "2024-02-29T23:59:59"},
+		{date: "2025-02-29", wantErr: true},
+		{date: "09/15/2025", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			clock, err := fixedClock(tt.date)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fixedClock(%q) error = %v, wantErr %t", tt.date, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := clock().In(cfg.Location).Format("2006-01-02T15:04:05"); got != tt.want {
+				t.Errorf("clock() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// laborDayBars are sessions from mid-August through September 2025, closing
+// one dollar higher each session: Aug 29 closes at 110 and Sep 2 at 111
+var laborDayBars = dailyBars("2025-08-15", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+
+// fetchFake runs getMTDReturn for AAA over laborDayBars
+func fetchFake(t *testing.T, start, end string, fopts FetchOptions) MTDResult {
+	t.Helper()
+	f := newFakeRun(t)
+	f.charts["AAA"] = laborDayBars
+	s, _ := time.ParseInLocation("2006-01-02", start, cfg.Location)
+	e, _ := time.ParseInLocation("2006-01-02", end, cfg.Location)
+	res, err := getMTDReturn(context.Background(), "AAA", s, e, fopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestPaddingHolidayStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		padding int
+	}{
+		{name: "no padding", padding: 0},
+		{name: "weekend padding", padding: 3},
+		{name: "week padding", padding: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sep 1 is Labor Day, so the first session in the window is Sep 2
+			res := fetchFake(t, "2025-09-01", "2025-09-30", FetchOptions{PaddingDays: tt.padding})
+			if got := res.BaselineDate.Format("2006-01-02"); got != "2025-09-02" {
+				t.Errorf("baseline date = %s, want 2025-09-02", got)
+			}
+			if got := res.FirstClose.InexactFloat64(); got != 111 {
+				t.Errorf("first close = %v, want 111", got)
+			}
+			if res.BarCount != 21 {
+				t.Errorf("bar count = %d, want the window's 21 sessions", res.BarCount)
+			}
+		})
+	}
+}
+
+func TestBaselineStrategies(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		baseline  string
+		wantDate  string
+		wantFirst float64
+	}{
+		{name: "first in window", start: "2025-09-01", baseline: baselineFirstInWindow, wantDate: "2025-09-02", wantFirst: 111},
+		{name: "prior close", start: "2025-09-01", baseline: baselinePriorClose, wantDate: "2025-08-29", wantFirst: 110},
+		{name: "nearest after a holiday", start: "2025-09-01", baseline: baselineNearest, wantDate: "2025-09-02", wantFirst: 111},
+		{name: "nearest on a Saturday", start: "2025-09-06", baseline: baselineNearest, wantDate: "2025-09-05", wantFirst: 114},
+		{name: "prior close mid month", start: "2025-09-10", baseline: baselinePriorClose, wantDate: "2025-09-09", wantFirst: 116},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := fetchFake(t, tt.start, "2025-09-30", FetchOptions{Baseline: tt.baseline})
+			if got := res.BaselineDate.Format("2006-01-02"); got != tt.wantDate {
+				t.Errorf("baseline date = %s, want %s", got, tt.wantDate)
+			}
+			if got := res.FirstClose.InexactFloat64(); got != tt.wantFirst {
+				t.Errorf("first close = %v, want %v", got, tt.wantFirst)
+			}
+			if want := 131/tt.wantFirst - 1; math.Abs(res.Return-want) > 1e-12 {
+				t.Errorf("return = %v, want %v", res.Return, want)
+			}
+		})
+	}
+}
+
+func TestUnknownSectorModes(t *testing.T) {
+	tests := []struct {
+		mode           string
+		wantSector     string // BBB's sector in the results
+		wantUnknownRow bool   // Whether the CSV's sector section has an Unknown row
+	}{
+		{mode: "", wantSector: unknownSector, wantUnknownRow: true},
+		{mode: unknownKeep, wantSector: unknownSector, wantUnknownRow: true},
+		{mode: unknownDrop, wantSector: unknownSector, wantUnknownRow: false},
+		{mode: unknownBackfill, wantSector: "Energy", wantUnknownRow: false},
+		{mode: "bogus", wantSector: unknownSector, wantUnknownRow: true},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = laborDayBars
+			f.charts["BBB"] = laborDayBars
+			ref := filepath.Join(t.TempDir(), "constituents.csv")
+			if err := os.WriteFile(ref, []byte("symbol,sector,name\nBBB,Energy,Beta\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			withConfig(t, func(c *Config) { c.UnknownSector, c.Russell1000CSV = tt.mode, ref })
+
+			u := Universe{}
+			u.add("AAA", "Tech", "")
+			u.add("BBB", unknownSector, "")
+			results, _, err := getMTDResults(2025, time.September, 1, RunOptions{
+				Source: func() (Universe, error) { return u, nil },
+				Now:    func() time.Time { return time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultFor(t, results, "BBB").Sector; got != tt.wantSector {
+				t.Errorf("BBB sector = %q, want %q", got, tt.wantSector)
+			}
+			data, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hasRow := strings.Contains("\n"+string(data), "\n"+unknownSector+",")
+			if hasRow != tt.wantUnknownRow {
+				t.Errorf("Unknown sector row = %t, want %t in:\n%s", hasRow, tt.wantUnknownRow, data)
+			}
+		})
+	}
+}
+
+func TestForwardFillHalt(t *testing.T) {
+	// AAA doesn't trade Sep 10-12
+	var halted []fakeBar
+	for _, b := range laborDayBars {
+		if b.date < "2025-09-10" || b.date > "2025-09-12" {
+			halted = append(halted, b)
+		}
+	}
+	tests := []struct {
+		name       string
+		fill       bool
+		wantSeries int
+	}{
+		{name: "without fill", fill: false, wantSeries: 18},
+		{name: "with fill", fill: true, wantSeries: 21},
+	}
+	var vols []float64
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = halted
+			res, err := getMTDReturn(context.Background(), "AAA", date(t, "2025-09-01"), date(t, "2025-09-30"),
+				FetchOptions{Series: true, ForwardFill: tt.fill, Metrics: allMetrics})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Series) != tt.wantSeries {
+				t.Errorf("series has %d points, want %d", len(res.Series), tt.wantSeries)
+			}
+			if res.BarCount != 18 {
+				t.Errorf("bar count = %d, want the 18 actual bars", res.BarCount)
+			}
+			if want := 131.0/111 - 1; math.Abs(res.Return-want) > 1e-12 {
+				t.Errorf("return = %v, want %v from the actual closes", res.Return, want)
+			}
+			if res.Volatility == nil {
+				t.Fatal("no volatility")
+			}
+			vols = append(vols, *res.Volatility)
+		})
+	}
+	if len(vols) == 2 && vols[0] == vols[1] {
+		t.Errorf("forward-filled volatility %v matches the unfilled one", vols[1])
+	}
+}
+
+func TestRunForwardFillOption(t *testing.T) {
+	// AAA doesn't trade Sep 10-12
+	var halted []fakeBar
+	for _, b := range laborDayBars {
+		if b.date < "2025-09-10" || b.date > "2025-09-12" {
+			halted = append(halted, b)
+		}
+	}
+	on, off := true, false
+	tests := []struct {
+		name     string
+		cfgFill  bool
+		fill     *bool
+		wantFill bool
+	}{
+		{name: "unset uses config off", cfgFill: false, fill: nil, wantFill: false},
+		{name: "unset uses config on", cfgFill: true, fill: nil, wantFill: true},
+		{name: "request turns fill on", cfgFill: false, fill: &on, wantFill: true},
+		{name: "request turns fill off", cfgFill: true, fill: &off, wantFill: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = halted
+			withConfig(t, func(c *Config) { c.ForwardFill = tt.cfgFill; c.Metrics = "" })
+			want, err := getMTDReturn(context.Background(), "AAA", date(t, "2025-09-01"), date(t, "2025-09-30"),
+				FetchOptions{ForwardFill: tt.wantFill, Metrics: allMetrics})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, _, err := runFake(t, []string{"AAA"}, RunOptions{ForwardFill: tt.fill})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := resultFor(t, results, "AAA").Volatility
+			if got == nil || want.Volatility == nil || math.Abs(*got-*want.Volatility) > 1e-12 {
+				t.Errorf("volatility = %v, want %v with fill %v", got, want.Volatility, tt.wantFill)
+			}
+		})
+	}
+}
+
+// csvTickers reads the ticker column of the first n rows after the header
+func csvTickers(t *testing.T, path string, n int) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tickers []string
+	for _, rec := range records[1 : n+1] {
+		tickers = append(tickers, rec[0])
+	}
+	return tickers
+}
+
+func TestWriteResultsCSVSort(t *testing.T) {
+	// Callers pass results sorted by return, descending
+	results := []Result{
+		{Ticker: "MMM", Sector: "Industrials", Return: 0.3},
+		{Ticker: "AAPL", Sector: "Tech", Return: 0.1},
+		{Ticker: "ZTS", Sector: "Health", Return: -0.2},
+	}
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{sortBy: "", want: []string{"MMM", "AAPL", "ZTS"}},
+		{sortBy: csvSortReturn, want: []string{"MMM", "AAPL", "ZTS"}},
+		{sortBy: csvSortTicker, want: []string{"AAPL", "MMM", "ZTS"}},
+	}
+	for _, tt := range tests {
+		t.Run("sort "+tt.sortBy, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.csv")
+			if err := writeResultsToCSV(results, calculateSectorReturns(results), path, CSVOptions{SortBy: tt.sortBy}); err != nil {
+				t.Fatal(err)
+			}
+			if got := csvTickers(t, path, len(results)); !slices.Equal(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+			if results[0].Ticker != "MMM" {
+				t.Error("sorting reordered the caller's results")
+			}
+		})
+	}
+}
+
+// failingWriter accepts limit bytes, then fails every write
+type failingWriter struct {
+	limit, written int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		n := w.limit - w.written
+		w.written = w.limit
+		return n, errors.New("disk full")
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestWriteResultsCSVWriteErrors(t *testing.T) {
+	results := []Result{{Ticker: "AAA", Sector: "Tech", Return: 0.1}, {Ticker: "BBB", Sector: "Energy", Return: 0.2}}
+	sectors := calculateSectorReturns(results)
+	var full strings.Builder
+	if err := writeResultsCSV(&full, results, sectors, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		wantErr bool
+	}{
+		{name: "fails immediately", limit: 0, wantErr: true},
+		{name: "fails mid-file", limit: full.Len() / 2, wantErr: true},
+		{name: "fails on the last byte", limit: full.Len() - 1, wantErr: true},
+		{name: "fits", limit: full.Len()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeResultsCSV(&failingWriter{limit: tt.limit}, results, sectors, "", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("writeResultsCSV error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteCSVFileKeepsOldFileOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		write   func(w io.Writer) error
+		want    string
+		wantErr bool
+	}{
+		{name: "failed write", write: func(w io.Writer) error {
+			io.WriteString(w, "partial")
+			return errors.New("disk full")
+		}, want: "previous run\n", wantErr: true},
+		{name: "successful write", write: func(w io.Writer) error {
+			_, err := io.WriteString(w, "new run\n")
+			return err
+		}, want: "new run\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeCSVFile(path, tt.write)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeCSVFile error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got, _ := os.ReadFile(path); string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files in the directory, want only the CSV (no temp files)", len(entries))
+			}
+		})
+	}
+}
+
+func TestBarPrice(t *testing.T) {
+	d := decimal.NewFromFloat
+	tests := []struct {
+		name            string
+		high, low, last float64
+		basis           string
+		want            float64
+	}{
+		{name: "close", high: 12, low: 6, last: 9, basis: basisClose, want: 9},
+		{name: "default basis", high: 12, low: 6, last: 9, basis: "", want: 9},
+		{name: "typical price", high: 12, low: 6, last: 9, basis: basisVWAP, want: 9},
+		{name: "typical price off close", high: 24, low: 12, last: 15, basis: basisVWAP, want: 17},
+		{name: "no high or low", high: 0, low: 0, last: 15, basis: basisVWAP, want: 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bar := &finance.ChartBar{High: d(tt.high), Low: d(tt.low), Close: d(tt.last)}
+			if got := barPrice(bar, tt.basis).InexactFloat64(); got != tt.want {
+				t.Errorf("barPrice = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVWAPReturn(t *testing.T) {
+	bars := []fakeBar{
+		{date: "2025-09-02", close: 9, high: 12, low: 6},   // Typical price 9
+		{date: "2025-09-15", close: 30, high: 40, low: 5},  // Ignored: only the ends count
+		{date: "2025-09-30", close: 15, high: 24, low: 12}, // Typical price 17
+	}
+	tests := []struct {
+		basis string
+		want  float64
+	}{
+		{basis: basisClose, want: 15.0/9 - 1},
+		{basis: basisVWAP, want: 17.0/9 - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.basis, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = bars
+			res, err := getMTDReturn(context.Background(), "AAA", septemberStart, septemberEnd, FetchOptions{ReturnBasis: tt.basis})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(res.Return-tt.want) > 1e-12 {
+				t.Errorf("return = %v, want %v", res.Return, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeometricMeanReturn(t *testing.T) {
+	tests := []struct {
+		name      string
+		returns   []float64
+		wantArith float64
+		wantGeo   float64
+	}{
+		{name: "equal returns", returns: []float64{0.1, 0.1}, wantArith: 0.1, wantGeo: 0.1},
+		{name: "up and down", returns: []float64{0.5, -0.5}, wantArith: 0, wantGeo: math.Sqrt(1.5*0.5) - 1},
+		{name: "volatile group", returns: []float64{1, -0.5, 0.2}, wantArith: 0.7 / 3, wantGeo: math.Cbrt(2*0.5*1.2) - 1},
+		{name: "wipeout skipped", returns: []float64{-1, 0.21}, wantArith: -0.395, wantGeo: 0.21},
+		{name: "only wipeouts", returns: []float64{-1}, wantArith: -1, wantGeo: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []Result
+			for _, r := range tt.returns {
+				results = append(results, Result{Sector: "Tech", Return: r})
+			}
+			results = append(results, Result{Sector: "Tech", Return: math.NaN()})
+			got := calculateSectorReturns(results)
+			if len(got) != 1 {
+				t.Fatalf("got %d sectors, want 1", len(got))
+			}
+			if math.Abs(got[0].AvgReturn-tt.wantArith) > 1e-12 {
+				t.Errorf("AvgReturn = %v, want %v", got[0].AvgReturn, tt.wantArith)
+			}
+			if math.Abs(got[0].GeometricMeanReturn-tt.wantGeo) > 1e-12 {
+				t.Errorf("GeometricMeanReturn = %v, want %v", got[0].GeometricMeanReturn, tt.wantGeo)
+			}
+		})
+	}
+}
+
+func TestClampEnd(t *testing.T) {
+	tests := []struct {
+		name          string
+		now           time.Time
+		lastBar       string
+		clamp         bool
+		wantEnd       string
+		wantRequested string // Empty when the end wasn't moved
+	}{
+		{name: "future end clamped", now: time.Date(2025, 9, 17, 22, 0, 0, 0, time.UTC), lastBar: "2025-09-16",
+			clamp: true, wantEnd: "2025-09-16", wantRequested: "2025-09-30"},
+		{name: "future end kept without the option", now: time.Date(2025, 9, 17, 22, 0, 0, 0, time.UTC), lastBar: "2025-09-16",
+			wantEnd: "2025-09-30"},
+		{name: "complete window not clamped", now: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC), lastBar: "2025-09-30",
+			clamp: true, wantEnd: "2025-09-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			for _, b := range laborDayBars {
+				if b.date <= tt.lastBar {
+					f.charts["AAA"] = append(f.charts["AAA"], b)
+				}
+			}
+			_, summary, err := runFake(t, []string{"AAA"}, RunOptions{ClampEnd: tt.clamp, Now: func() time.Time { return tt.now }})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary.End.Format("2006-01-02"); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+			requested := ""
+			if summary.RequestedEnd != nil {
+				requested = summary.RequestedEnd.Format("2006-01-02")
+			}
+			if requested != tt.wantRequested {
+				t.Errorf("requested end = %q, want %q", requested, tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestBadBars(t *testing.T) {
+	septemberBars := func(bad map[int]float64) []fakeBar {
+		bars := dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+		for i, c := range bad {
+			bars[i].close = c
+		}
+		return bars
+	}
+	tests := []struct {
+		name    string
+		bad     map[int]float64
+		want    float64
+		wantBar int
+		wantErr bool
+	}{
+		{name: "clean", want: 120.0/100 - 1, wantBar: 21},
+		{name: "zero and negative close skipped", bad: map[int]float64{5: 0, 10: -3}, want: 120.0/100 - 1, wantBar: 19},
+		{name: "bad first close skipped", bad: map[int]float64{0: 0}, want: 120.0/101 - 1, wantBar: 20},
+		{name: "bad last close skipped", bad: map[int]float64{20: -1}, want: 119.0/100 - 1, wantBar: 20},
+		{name: "too many bad closes", bad: map[int]float64{1: 0, 2: -1, 3: 0, 4: -1, 5: 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = septemberBars(tt.bad)
+			res, err := getMTDReturn(context.Background(), "AAA", septemberStart, septemberEnd, defaultFetchOptions())
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadData) || !math.IsNaN(res.Return) {
+					t.Errorf("got %v, %v; want ErrBadData and NaN", res.Return, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(res.Return-tt.want) > 1e-12 || res.BarCount != tt.wantBar {
+				t.Errorf("got return %v over %d bars, want %v over %d", res.Return, res.BarCount, tt.want, tt.wantBar)
+			}
+		})
+	}
+}
+
+func TestRunRecordsBaseline(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseline   string
+		cfgDefault string
+		want       string
+		wantDate   string
+	}{
+		{name: "first in window", baseline: baselineFirstInWindow, want: baselineFirstInWindow, wantDate: "2025-09-02"},
+		{name: "prior close", baseline: baselinePriorClose, want: baselinePriorClose, wantDate: "2025-08-29"},
+		{name: "nearest", baseline: baselineNearest, want: baselineNearest, wantDate: "2025-09-02"},
+		{name: "config default", cfgDefault: baselinePriorClose, want: baselinePriorClose, wantDate: "2025-08-29"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			if tt.cfgDefault != "" {
+				withConfig(t, func(c *Config) { c.Baseline = tt.cfgDefault })
+			}
+			f.charts["AAA"] = laborDayBars
+			results, _, err := runFake(t, []string{"AAA"}, RunOptions{Baseline: tt.baseline})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r := resultFor(t, results, "AAA"); r.Baseline != tt.want || r.BaselineDate != tt.wantDate {
+				t.Errorf("baseline %s on %s, want %s on %s", r.Baseline, r.BaselineDate, tt.want, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestBenchmarkCSVRow(t *testing.T) {
+	tests := []struct {
+		name      string
+		benchmark string
+		row       bool
+		wantRows  int
+	}{
+		{name: "benchmark row", benchmark: "SPY", row: true, wantRows: 1},
+		{name: "row toggled off", benchmark: "SPY", row: false, wantRows: 0},
+		{name: "no benchmark", benchmark: "", row: true, wantRows: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			withConfig(t, func(c *Config) { c.Benchmark, c.CSVBenchmarkRow = tt.benchmark, tt.row })
+			f.charts["AAA"] = laborDayBars
+			f.charts["SPY"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 500 - float64(i) })
+
+			results, summary, err := runFake(t, []string{"AAA"}, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Errorf("benchmark leaked into results %+v", results)
+			}
+			if (summary.Benchmark != nil) != (tt.benchmark != "") {
+				t.Errorf("summary benchmark = %+v", summary.Benchmark)
+			}
+			data, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := csv.NewReader(strings.NewReader(string(data)))
+			r.FieldsPerRecord = -1 // The sections have different widths
+			rows, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers, spy := 0, 0
+			for _, row := range rows {
+				switch row[0] {
+				case "Benchmark":
+					headers++
+				case "SPY":
+					spy++
+				case "Tech":
+					if len(row) == 3 && row[2] != "1" {
+						t.Errorf("Tech sector counts %s tickers, want 1", row[2])
+					}
+				}
+			}
+			if headers != tt.wantRows || spy != tt.wantRows {
+				t.Errorf("%d benchmark headers and %d SPY rows, want %d in:\n%s", headers, spy, tt.wantRows, data)
+			}
+		})
+	}
+}
+
+// TestConcurrentCSVWrites has writers race to replace one CSV while a reader
+// checks every version it sees is one writer's complete file
+func TestConcurrentCSVWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	const writers = 8
+	want := make(map[string]bool, writers)
+	runs := make([][]Result, writers)
+	for i := range runs {
+		for j := range 50 {
+			runs[i] = append(runs[i], Result{Ticker: fmt.Sprintf("W%dT%02d", i, j), Sector: "Tech", Return: float64(i) / 100})
+		}
+		var b strings.Builder
+		if err := writeResultsCSV(&b, runs[i], calculateSectorReturns(runs[i]), "", nil); err != nil {
+			t.Fatal(err)
+		}
+		want[b.String()] = true
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil || !want[string(data)] {
+				t.Errorf("read a torn CSV (%v):\n%.200s", err, data)
+				return
+			}
+		}
+	}()
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := writeResultsToCSV(runs[i], calculateSectorReturns(runs[i]), path, CSVOptions{}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-readerDone
+
+	data, err := os.ReadFile(path)
+	if err != nil || !want[string(data)] {
+		t.Errorf("final CSV isn't one writer's output (%v)", err)
+	}
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) > 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
+
+func TestAnnualizeReturn(t *testing.T) {
+	tests := []struct {
+		name       string
+		ret        float64
+		sessions   int
+		returnType string
+		want       float64
+	}{
+		{name: "a year of sessions", ret: 0.1, sessions: tradingDaysPerYear, returnType: returnSimple, want: 0.1},
+		{name: "half a year compounds", ret: 0.1, sessions: tradingDaysPerYear / 2, returnType: returnSimple, want: 0.21},
+		{name: "log scales linearly", ret: 0.1, sessions: tradingDaysPerYear / 4, returnType: returnLog, want: 0.4},
+		{name: "September's sessions", ret: 0.01, sessions: 21, returnType: returnSimple, want: math.Pow(1.01, 12) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := annualizeReturn(tt.ret, tt.sessions, tt.returnType); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("annualizeReturn = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTradingDays(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		want      int
+		annualize bool
+	}{
+		{name: "whole month", now: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC), want: 21, annualize: true},
+		{name: "mid month", now: time.Date(2025, 9, 10, 21, 0, 0, 0, time.UTC), want: 7},
+		{name: "not annualized", now: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC), want: 21},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = laborDayBars
+			results, summary, err := runFake(t, []string{"AAA"}, RunOptions{
+				Now:       func() time.Time { return tt.now },
+				Annualize: tt.annualize,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.TradingDays != tt.want {
+				t.Errorf("trading days = %d, want %d", summary.TradingDays, tt.want)
+			}
+			r := resultFor(t, results, "AAA")
+			if !tt.annualize {
+				if r.AnnualizedReturn != nil {
+					t.Errorf("annualized return %v without Annualize", *r.AnnualizedReturn)
+				}
+				return
+			}
+			if want := annualizeReturn(r.Return, tt.want, returnSimple); r.AnnualizedReturn == nil || math.Abs(*r.AnnualizedReturn-want) > 1e-12 {
+				t.Errorf("annualized return = %v, want %v", r.AnnualizedReturn, want)
+			}
+		})
+	}
+}
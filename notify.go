@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// webhookTimeout bounds how long a notification may take to deliver
+const webhookTimeout = 5 * time.Second
+
+// notifications tracks webhook deliveries still in flight, so cli mode can
+// wait for them before exiting
+var notifications sync.WaitGroup
+
+// webhookPayload is the JSON body POSTed to the configured webhook.
+// Text makes it render directly in Slack incoming webhooks.
+type webhookPayload struct {
+	Text        string  `json:"text"`
+	Level       string  `json:"level"` // "info" or "failure"
+	RunID       string  `json:"run_id"`
+	IndexReturn float64 `json:"index_return"`
+	Tickers     int     `json:"tickers"`
+	Failed      int     `json:"failed"`
+	FailureRate float64 `json:"failure_rate"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// notifyRun posts a best-effort run summary to the configured webhook in the
+// background, so a slow webhook never delays the run. Delivery failures are
+// logged and never affect the run result.
+func notifyRun(url string, summary RunSummary, runErr error) {
+	if url == "" {
+		return
+	}
+
+	payload := webhookPayload{
+		Level:       "info",
+		RunID:       summary.RunID,
+		IndexReturn: summary.IndexReturn,
+		Tickers:     summary.Tickers,
+		Failed:      summary.Failed,
+		FailureRate: summary.FailureRate(),
+	}
+	switch {
+	case runErr != nil:
+		payload.Level = "failure"
+		payload.Error = runErr.Error()
+		payload.Text = fmt.Sprintf("❌ Refresh %s failed: %v", summary.RunID, runErr)
+	case payload.FailureRate > cfg.MaxFailureRate:
+		payload.Level = "failure"
+		payload.Text = fmt.Sprintf("❌ Refresh %s: %d/%d tickers failed (%.1f%%)",
+			summary.RunID, summary.Failed, summary.Tickers, payload.FailureRate*100)
+	default:
+		payload.Text = fmt.Sprintf("✅ Refresh %s complete: index %.2f%%, %d/%d tickers failed",
+			summary.RunID, summary.IndexReturn*100, summary.Failed, summary.Tickers)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Warning: failed to encode webhook payload: %v", err)
+		return
+	}
+
+	notifications.Add(1)
+	go func() {
+		defer notifications.Done()
+		resp, err := webhookClient().Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Warning: webhook notification failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Warning: webhook returned status %d", resp.StatusCode)
+		}
+	}()
+}
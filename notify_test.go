@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotifyRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		summary   RunSummary
+		runErr    error
+		wantLevel string
+	}{
+		{name: "complete", summary: RunSummary{RunID: "r1", Tickers: 100, Failed: 1}, wantLevel: "info"},
+		{name: "too many failures", summary: RunSummary{RunID: "r2", Tickers: 100, Failed: 50}, wantLevel: "failure"},
+		{name: "run error", summary: RunSummary{RunID: "r3"}, runErr: errors.New("scrape failed"), wantLevel: "failure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.MaxFailureRate = 0.1 })
+			release := make(chan struct{})
+			received := make(chan webhookPayload, 1)
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var p webhookPayload
+				if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+					t.Error(err)
+				}
+				<-release // Hold the response until notifyRun has returned
+				received <- p
+			}))
+			defer hook.Close()
+
+			returned := make(chan struct{})
+			go func() {
+				notifyRun(hook.URL, tt.summary, tt.runErr)
+				close(returned)
+			}()
+			select {
+			case <-returned:
+			case <-time.After(time.Second):
+				t.Fatal("notifyRun waited on the webhook")
+			}
+			close(release)
+			notifications.Wait()
+
+			select {
+			case p := <-received:
+				if p.RunID != tt.summary.RunID || p.Level != tt.wantLevel {
+					t.Errorf("payload %+v, want run %s at level %s", p, tt.summary.RunID, tt.wantLevel)
+				}
+			default:
+				t.Fatal("webhook received nothing")
+			}
+		})
+	}
+}
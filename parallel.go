@@ -29,14 +29,21 @@ func ProcessInParallel[T any, R any](
 		maxWorkers = len(items)
 	}
 
-	// Create channels for work distribution
-	jobs := make(chan T, len(items))
-	results := make(chan struct {
+	// Jobs carry their input index so results can be placed directly
+	type job struct {
+		index int
+		item  T
+	}
+	type jobResult struct {
 		index int
 		item  T
 		value R
 		err   error
-	}, len(items))
+	}
+
+	// Create channels for work distribution
+	jobs := make(chan job, len(items))
+	results := make(chan jobResult, len(items))
 
 	// Start worker goroutines
 	var wg sync.WaitGroup
@@ -44,19 +51,15 @@ func ProcessInParallel[T any, R any](
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for item := range jobs {
+			for j := range jobs {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					result, err := processFunc(item)
-					results <- struct {
-						index int
-						item  T
-						value R
-						err   error
-					}{
-						item:  item,
+					result, err := safeProcess(processFunc, j.item)
+					results <- jobResult{
+						index: j.index,
+						item:  j.item,
 						value: result,
 						err:   err,
 					}
@@ -66,9 +69,9 @@ func ProcessInParallel[T any, R any](
 	}
 
 	// Send jobs to workers
-	for _, item := range items {
+	for i, item := range items {
 		select {
-		case jobs <- item:
+		case jobs <- job{index: i, item: item}:
 		case <-ctx.Done():
 			close(jobs)
 			return nil, []error{ctx.Err()}
@@ -92,14 +95,14 @@ func ProcessInParallel[T any, R any](
 			errors = append(errors, result.err)
 			continue
 		}
-		// Find the index of the item in the original slice
-		for i, item := range items {
-			if any(item) == any(result.item) {
-				resultSlice[i] = result.value
-				break
-			}
-		}
+		resultSlice[result.index] = result.value
 	}
 
 	return resultSlice, errors
-}
\ No newline at end of file
+}
+
+// safeProcess calls processFunc, converting a panic into an error for that item
+func safeProcess[T any, R any](processFunc func(T) (R, error), item T) (result R, err error) {
+	defer recoverAsError(&err, item)
+	return processFunc(item)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// BenchmarkProcessInParallel measures the pool's overhead per item with a
+// trivial process function. Results are placed by index, so ns/op should
+// grow linearly with the item count; the 10k case makes any quadratic
+// collection cost obvious.
+func BenchmarkProcessInParallel(b *testing.B) {
+	double := func(n int) (int, error) { return n * 2, nil }
+	for _, items := range []int{100, 1000, 10000} {
+		input := make([]int, items)
+		for i := range input {
+			input[i] = i
+		}
+		for _, workers := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("items=%d/workers=%d", items, workers), func(b *testing.B) {
+				for b.Loop() {
+					results, errs := ProcessInParallel(context.Background(), input, double, workers)
+					if len(errs) > 0 || len(results) != items || results[items-1] != (items-1)*2 {
+						b.Fatalf("got %d results, %d errors", len(results), len(errs))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestProcessInParallel(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []int
+		workers int
+		want    []int
+		errs    int
+	}{
+		{name: "empty", items: nil, workers: 4, want: nil},
+		{name: "keeps input order", items: []int{5, 3, 9, 1}, workers: 3, want: []int{10, 6, 18, 2}},
+		{name: "more workers than items", items: []int{1, 2}, workers: 16, want: []int{2, 4}},
+		{name: "default workers", items: []int{7}, workers: 0, want: []int{14}},
+		{name: "errors leave zero values", items: []int{1, -1, 3}, workers: 2, want: []int{2, 0, 6}, errs: 1},
+		{name: "panics become errors", items: []int{0, 2}, workers: 2, want: []int{0, 4}, errs: 1},
+	}
+	process := func(n int) (int, error) {
+		switch {
+		case n < 0:
+			return 0, fmt.Errorf("negative %d", n)
+		case n == 0:
+			panic("zero")
+		}
+		return n * 2, nil
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := ProcessInParallel(context.Background(), tt.items, process, tt.workers)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+			if len(errs) != tt.errs {
+				t.Errorf("got %d errors (%v), want %d", len(errs), errs, tt.errs)
+			}
+		})
+	}
+}
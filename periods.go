@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Supported return periods, in display order
+const (
+	periodMTD = "mtd"
+	periodQTD = "qtd"
+	periodYTD = "ytd"
+)
+
+var knownPeriods = []string{periodMTD, periodQTD, periodYTD}
+
+// parsePeriods validates a comma-separated period list such as "mtd,qtd,ytd"
+func parsePeriods(s string) ([]string, error) {
+	var periods []string
+	seen := make(map[string]bool)
+	for _, p := range splitList(strings.ToLower(s)) {
+		if p != periodMTD && p != periodQTD && p != periodYTD {
+			return nil, fmt.Errorf("unknown period %q (expected mtd, qtd or ytd)", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			periods = append(periods, p)
+		}
+	}
+	return periods, nil
+}
+
+// periodStarts maps each period to its start date relative to the window start.
+// MTD is the window itself, QTD starts on the first day of its quarter and YTD on January 1.
+func periodStarts(periods []string, start time.Time) map[string]time.Time {
+	if len(periods) == 0 {
+		return nil
+	}
+	starts := make(map[string]time.Time, len(periods))
+	for _, p := range periods {
+		switch p {
+		case periodMTD:
+			starts[p] = start
+		case periodQTD:
+			quarterMonth := time.Month((int(start.Month())-1)/3*3 + 1)
+			starts[p] = time.Date(start.Year(), quarterMonth, 1, 0, 0, 0, 0, start.Location())
+		case periodYTD:
+			starts[p] = time.Date(start.Year(), time.January, 1, 0, 0, 0, 0, start.Location())
+		}
+	}
+	return starts
+}
+
+// setPeriodReturns copies computed period returns onto the result
+func setPeriodReturns(r *Result, returns map[string]float64) {
+	for p, v := range returns {
+		if math.IsNaN(v) {
+			continue
+		}
+		switch p {
+		case periodMTD:
+			r.ReturnMTD = &v
+		case periodQTD:
+			r.ReturnQTD = &v
+		case periodYTD:
+			r.ReturnYTD = &v
+		}
+	}
+}
+
+// periodReturn returns the result's return for the named period, if computed
+func periodReturn(r Result, period string) *float64 {
+	switch period {
+	case periodMTD:
+		return r.ReturnMTD
+	case periodQTD:
+		return r.ReturnQTD
+	case periodYTD:
+		return r.ReturnYTD
+	}
+	return nil
+}
+
+// resultPeriods lists the periods present on any of the results, in display order
+func resultPeriods(results []Result) []string {
+	var periods []string
+	for _, p := range knownPeriods {
+		for _, r := range results {
+			if periodReturn(r, p) != nil {
+				periods = append(periods, p)
+				break
+			}
+		}
+	}
+	return periods
+}
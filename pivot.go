@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// pivotHeader returns the pivot CSV's column names
+func pivotHeader(units string) []string {
+	return []string{
+		"Sector",
+		returnColumn("Avg_Return", units),
+		returnColumn("Median_Return", units),
+		returnColumn("Std_Dev", units),
+		"Ticker_Count",
+		returnColumn("Contribution", units),
+	}
+}
+
+// sectorPivotRow is one sector's metrics in the pivot CSV
+type sectorPivotRow struct {
+	SectorReturn
+	Median       float64
+	StdDev       float64 // Sample standard deviation; NaN for a single ticker
+	Contribution float64 // Share of the index return, weighted like AvgReturn
+}
+
+// sectorPivot extends sectorReturns with each sector's median, standard
+// deviation and contribution, in the same order. Contributions sum to the
+// mean return across the sectors given, so they add up to the index return
+// whenever every sector is included.
+func sectorPivot(results []Result, sectorReturns []SectorReturn) []sectorPivotRow {
+	members := make(map[string][]float64, len(sectorReturns))
+	weights := make(map[string]float64, len(sectorReturns))
+	for _, sr := range sectorReturns {
+		members[sr.Sector] = nil
+	}
+	totalWeight := 0.0
+	for _, r := range results {
+		if _, ok := members[r.Sector]; !ok || math.IsNaN(r.Return) {
+			continue
+		}
+		w := 1.0
+		if r.Weight != nil {
+			w = *r.Weight
+		}
+		members[r.Sector] = append(members[r.Sector], r.Return)
+		weights[r.Sector] += w
+		totalWeight += w
+	}
+
+	rows := make([]sectorPivotRow, len(sectorReturns))
+	for i, sr := range sectorReturns {
+		returns := members[sr.Sector]
+		rows[i] = sectorPivotRow{SectorReturn: sr, Median: median(returns), StdDev: stdDev(returns)}
+		if totalWeight > 0 {
+			rows[i].Contribution = sr.AvgReturn * weights[sr.Sector] / totalWeight
+		}
+	}
+	return rows
+}
+
+// median returns the middle of returns, averaging the two middle values for
+// an even count; NaN when empty
+func median(returns []float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	sorted := slices.Clone(returns)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// stdDev returns the sample standard deviation of returns; NaN with fewer than two
+func stdDev(returns []float64) float64 {
+	var s seriesStats
+	for _, r := range returns {
+		s.addReturn(r)
+	}
+	if s.n < 2 {
+		return math.NaN()
+	}
+	return math.Sqrt(s.m2 / float64(s.n-1))
+}
+
+// writePivotToCSV writes one row per sector with a column per metric, a
+// more digestible layout than the sector section appended to the main CSV
+func writePivotToCSV(results []Result, sectorReturns []SectorReturn, filename string, opts CSVOptions) error {
+	return writeCSVFile(filename, func(w io.Writer) error {
+		return writePivotCSV(w, sectorPivot(results, sectorReturns), normalizeUnits(opts.Units))
+	})
+}
+
+// writePivotCSV writes the pivot rows to w, returning any write or flush error
+func writePivotCSV(w io.Writer, rows []sectorPivotRow, units string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(pivotHeader(units)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := writer.Write([]string{
+			row.Sector,
+			formatReturn(row.AvgReturn, units),
+			formatReturn(row.Median, units),
+			formatReturn(row.StdDev, units),
+			fmt.Sprintf("%d", row.TickerCount),
+			formatReturn(row.Contribution, units),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
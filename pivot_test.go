@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMedianStdDev(t *testing.T) {
+	tests := []struct {
+		name       string
+		returns    []float64
+		wantMedian float64
+		wantStdDev float64
+	}{
+		{name: "empty", wantMedian: math.NaN(), wantStdDev: math.NaN()},
+		{name: "single", returns: []float64{0.1}, wantMedian: 0.1, wantStdDev: math.NaN()},
+		{name: "odd count", returns: []float64{0.6, 0.1, 0.2}, wantMedian: 0.2, wantStdDev: math.Sqrt(0.07)},
+		{name: "even count", returns: []float64{0.4, -0.2, 0.1, 0.3}, wantMedian: 0.2, wantStdDev: math.Sqrt(0.21 / 3)},
+	}
+	same := func(a, b float64) bool { return math.IsNaN(a) && math.IsNaN(b) || math.Abs(a-b) < 1e-12 }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := slices.Clone(tt.returns)
+			if got := median(input); !same(got, tt.wantMedian) {
+				t.Errorf("median = %v, want %v", got, tt.wantMedian)
+			}
+			if !slices.Equal(input, tt.returns) {
+				t.Error("median reordered its input")
+			}
+			if got := stdDev(input); !same(got, tt.wantStdDev) {
+				t.Errorf("stdDev = %v, want %v", got, tt.wantStdDev)
+			}
+		})
+	}
+}
+
+func TestWritePivotToCSV(t *testing.T) {
+	results := []Result{
+		{Ticker: "AAA", Sector: "Tech", Return: 0.6},
+		{Ticker: "BBB", Sector: "Tech", Return: 0.2},
+		{Ticker: "CCC", Sector: "Tech", Return: 0.1},
+		{Ticker: "DDD", Sector: "Energy", Return: -0.1},
+	}
+	tests := []struct {
+		units string
+		want  [][]string
+	}{
+		{
+			units: unitsPercent,
+			want: [][]string{
+				{"Sector", "Avg_Return_%", "Median_Return_%", "Std_Dev_%", "Ticker_Count", "Contribution_%"},
+				{"Tech", "30.00%", "20.00%", "26.46%", "3", "22.50%"},
+				{"Energy", "-10.00%", "-10.00%", "", "1", "-2.50%"},
+			},
+		},
+		{
+			units: unitsBps,
+			want: [][]string{
+				{"Sector", "Avg_Return_bps", "Median_Return_bps", "Std_Dev_bps", "Ticker_Count", "Contribution_bps"},
+				{"Tech", "3000", "2000", "2646", "3", "2250"},
+				{"Energy", "-1000", "-1000", "", "1", "-250"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.units, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pivot.csv")
+			if err := writePivotToCSV(results, calculateSectorReturns(results), path, CSVOptions{Units: tt.units}); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			rows, err := csv.NewReader(f).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(rows[0], tt.want[0]) {
+				t.Errorf("header = %v, want %v", rows[0], tt.want[0])
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("%d rows, want one per sector: %v", len(rows)-1, rows)
+			}
+			for _, want := range tt.want[1:] {
+				i := slices.IndexFunc(rows, func(row []string) bool { return row[0] == want[0] })
+				if i < 0 || !slices.Equal(rows[i], want) {
+					t.Errorf("%s row = %v, want %v", want[0], rows[max(i, 0)], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWritesPivot(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		f := newFakeRun(t)
+		withConfig(t, func(c *Config) { c.CSVPivot = enabled })
+		f.charts["AAA"] = laborDayBars
+		if _, _, err := runFake(t, []string{"AAA"}, RunOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(outputPivot); (err == nil) != enabled {
+			t.Errorf("pivot enabled = %t, stat: %v", enabled, err)
+		}
+	}
+}
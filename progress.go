@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// etaWindow is the number of recent completions the ETA's rate is averaged over
+const etaWindow = 20
+
+// etaMinSamples is the number of completions needed before an ETA is reported
+const etaMinSamples = 5
+
+// RunProgress counts a running refresh's completed tickers. The counters are
+// updated atomically from the collection loop, so readers never wait on a
+// refresh. The methods are no-ops on a nil *RunProgress.
+type RunProgress struct {
+	running atomic.Bool
+	done    atomic.Int64
+	total   atomic.Int64
+
+	// Recent completion times, for the ETA
+	mu     sync.Mutex
+	recent []time.Time
+}
+
+// begin resets the counters for a new run
+func (p *RunProgress) begin() {
+	if p == nil {
+		return
+	}
+	p.done.Store(0)
+	p.total.Store(0)
+	p.mu.Lock()
+	p.recent = p.recent[:0]
+	p.mu.Unlock()
+	p.running.Store(true)
+}
+
+// setTotal records the number of tickers in the run once the universe is known
+func (p *RunProgress) setTotal(n int) {
+	if p != nil {
+		p.total.Store(int64(n))
+	}
+}
+
+// advance marks n more tickers as finished without fetching them (e.g. resumed
+// from a checkpoint), so they don't affect the ETA
+func (p *RunProgress) advance(n int) {
+	if p != nil {
+		p.done.Add(int64(n))
+	}
+}
+
+// complete marks one fetched ticker as finished, successfully or not
+func (p *RunProgress) complete() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if len(p.recent) == etaWindow {
+		p.recent = append(p.recent[:0], p.recent[1:]...)
+	}
+	p.recent = append(p.recent, time.Now())
+	p.mu.Unlock()
+	p.done.Add(1)
+}
+
+// end marks the run as finished, leaving its final counts readable
+func (p *RunProgress) end() {
+	if p != nil {
+		p.running.Store(false)
+	}
+}
+
+// eta estimates the time left from the average rate of the recent
+// completions, reporting false until enough have been seen
+func (p *RunProgress) eta(remaining int64) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.recent) < etaMinSamples {
+		return 0, false
+	}
+	span := p.recent[len(p.recent)-1].Sub(p.recent[0])
+	perTicker := span / time.Duration(len(p.recent)-1)
+	// Count the time since the last completion against the next one
+	elapsed := time.Since(p.recent[len(p.recent)-1])
+	return max(time.Duration(remaining)*perTicker-elapsed, 0), true
+}
+
+// progressResponse is the JSON body returned by /api/progress
+type progressResponse struct {
+	Running bool  `json:"running"`
+	Done    int64 `json:"done"`
+	Total   int64 `json:"total"` // 0 until the ticker list is loaded
+
+	// ETASeconds is the estimated time left in a running refresh; null until
+	// enough tickers have finished to estimate the rate
+	ETASeconds *float64 `json:"eta_seconds"`
+}
+
+// handleProgress reports how far the running (or last) refresh has got
+func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
+	resp := progressResponse{
+		Running: s.progress.running.Load(),
+		Done:    s.progress.done.Load(),
+		Total:   s.progress.total.Load(),
+	}
+	if resp.Running && resp.Total > 0 {
+		if eta, ok := s.progress.eta(resp.Total - resp.Done); ok {
+			secs := eta.Round(time.Second).Seconds()
+			resp.ETASeconds = &secs
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
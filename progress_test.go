@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunProgressCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    func(p *RunProgress)
+		wantDone int64
+		wantRun  bool
+	}{
+		{name: "fresh run", steps: func(p *RunProgress) { p.begin(); p.setTotal(3) }, wantRun: true},
+		{name: "fetched and resumed", steps: func(p *RunProgress) {
+			p.begin()
+			p.setTotal(5)
+			p.advance(2)
+			p.complete()
+		}, wantDone: 3, wantRun: true},
+		{name: "ended keeps counts", steps: func(p *RunProgress) {
+			p.begin()
+			p.complete()
+			p.end()
+		}, wantDone: 1},
+		{name: "begin resets", steps: func(p *RunProgress) {
+			p.begin()
+			p.advance(4)
+			p.end()
+			p.begin()
+		}, wantRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p RunProgress
+			tt.steps(&p)
+			if p.done.Load() != tt.wantDone || p.running.Load() != tt.wantRun {
+				t.Errorf("done %d running %t, want %d %t", p.done.Load(), p.running.Load(), tt.wantDone, tt.wantRun)
+			}
+		})
+	}
+
+	// Runs without a progress tracker pass nil
+	var nilProgress *RunProgress
+	nilProgress.begin()
+	nilProgress.setTotal(1)
+	nilProgress.complete()
+	nilProgress.end()
+}
+
+func TestProgressDuringRefresh(t *testing.T) {
+	f := newFakeRun(t)
+	f.delay = 5 * time.Millisecond
+	var tickers []string
+	for i := range 60 {
+		ticker := fmt.Sprintf("T%02d", i)
+		tickers = append(tickers, ticker)
+		f.charts[ticker] = laborDayBars
+	}
+	writeTemplates(t, nil)
+	s := NewServer()
+
+	var u Universe
+	for _, ticker := range tickers {
+		u.add(ticker, "Tech", "")
+	}
+	refreshed := make(chan error)
+	go func() {
+		_, _, err := s.refresh(2025, time.September, 1, RunOptions{
+			Source: func() (Universe, error) { return u, nil },
+			Now:    func() time.Time { return time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC) },
+		})
+		refreshed <- err
+	}()
+
+	poll := func() progressResponse {
+		rec := httptest.NewRecorder()
+		s.handleProgress(rec, httptest.NewRequest(http.MethodGet, "/api/progress", nil))
+		var resp progressResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	var last int64
+	midway := false
+	for done := false; !done; {
+		select {
+		case err := <-refreshed:
+			if err != nil {
+				t.Fatal(err)
+			}
+			done = true
+		case <-time.After(time.Millisecond):
+			p := poll()
+			if p.Done < last {
+				t.Fatalf("progress went back from %d to %d", last, p.Done)
+			}
+			last = p.Done
+			if p.Running && p.Total == int64(len(tickers)) && p.Done > 0 && p.Done < p.Total {
+				midway = true
+			}
+		}
+	}
+	if !midway {
+		t.Error("never saw the refresh part-way through")
+	}
+	if p := poll(); p.Running || p.Done != int64(len(tickers)) || p.Total != int64(len(tickers)) {
+		t.Errorf("final progress %+v, want %d of %d, not running", p, len(tickers), len(tickers))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/quote"
+	"github.com/shopspring/decimal"
+)
+
+// Fetch modes: how each ticker's prices are requested
+const (
+	// fetchChart requests each ticker's daily chart; it handles any window
+	fetchChart = "chart"
+	// fetchQuote requests batched quotes, which carry only the latest price and
+	// the previous session's close. It serves one-session windows ending at
+	// the latest session with a fraction of the requests; any other window
+	// falls back to fetchChart.
+	fetchQuote = "quote"
+)
+
+// quoteBatchSize is the number of symbols requested per quote call
+const quoteBatchSize = 50
+
+// quoteIneligible explains why a run can't be served from quotes, or returns
+// "" when it can: the baseline must be the close of the session before the
+// latest one, and the return must need nothing but the two prices
+func quoteIneligible(start, expectedLast time.Time, fopts FetchOptions) string {
+	switch {
+	case len(fopts.PeriodStarts) > 0:
+		return "extra periods need the chart"
+	case fopts.ReturnBasis == basisVWAP:
+		return "vwap needs daily highs and lows"
+	case !expectedLast.Equal(lastTradingDay(time.Now().In(expectedLast.Location()), time.Now())):
+		return "the window ends before the latest session"
+	}
+	// On a trading-day start nearest is the first bar in the window; otherwise it depends on bar dates
+	if fopts.Baseline == baselineNearest && !isTradingDay(start) {
+		return "nearest needs the bars around a non-trading start"
+	}
+	if n := tradingDaysBetween(start, expectedLast); n != quoteSessions(fopts.Baseline) {
+		return fmt.Sprintf("the window spans %d sessions", n)
+	}
+	return ""
+}
+
+// quoteSessions is the number of sessions in a window a quote can serve: the
+// latest session, preceded by the baseline session unless the baseline is the
+// prior close from before the window
+func quoteSessions(baseline string) int {
+	if baseline == baselinePriorClose {
+		return 1
+	}
+	return 2
+}
+
+// fetchQuotes requests quotes for tickers in batches. Tickers missing from the
+// response, or in a batch that failed, are left out so the caller can fall
+// back to the chart for them.
+func fetchQuotes(ctx context.Context, tickers []string, fopts FetchOptions) map[string]MTDResult {
+	out := make(map[string]MTDResult, len(tickers))
+	for i := 0; i < len(tickers); i += quoteBatchSize {
+		batch := tickers[i:min(i+quoteBatchSize, len(tickers))]
+		iter := quote.ListP(&quote.Params{Params: finance.Params{Context: &ctx}, Symbols: batch})
+		for iter.Next() {
+			if res, ok := quoteResult(iter.Quote(), fopts.ReturnType, quoteSessions(fopts.Baseline)); ok {
+				out[iter.Quote().Symbol] = res
+			}
+		}
+		if err := iter.Err(); err != nil {
+			log.Printf("Warning: quote batch of %d tickers failed, falling back to charts: %v", len(batch), err)
+		}
+	}
+	return out
+}
+
+// quoteResult converts a quote into the one-session result, counting the
+// window's sessions as bars the chart would have returned, and reports false
+// when it lacks a price or previous close
+func quoteResult(q *finance.Quote, returnType string, sessions int) (MTDResult, bool) {
+	if q.RegularMarketPrice <= 0 || q.RegularMarketPreviousClose <= 0 {
+		return MTDResult{Return: math.NaN()}, false
+	}
+	first := decimal.NewFromFloat(q.RegularMarketPreviousClose)
+	last := decimal.NewFromFloat(q.RegularMarketPrice)
+	lastTime := time.Unix(int64(q.RegularMarketTime), 0).In(cfg.Location)
+	up, down := 0, 0
+	switch last.Cmp(first) {
+	case 1:
+		up = 1
+	case -1:
+		down = 1
+	}
+	return MTDResult{
+		Return:      computeReturn(first, last, returnType),
+		BarCount:    sessions,
+		FirstClose:  first,
+		LastClose:   last,
+		PriceChange: last.Sub(first),
+		UpDays:      up,
+		DownDays:    down,
+		LastBarTime: lastTime,
+		AvgVolume:   float64(q.RegularMarketVolume),
+
+		BaselineDate: tradingDaysBack(lastTime, 1),
+		Currency:     q.CurrencyID,
+	}, true
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+)
+
+func TestQuoteResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, last float64
+		returnType string
+		want       float64
+		wantUp     int
+		wantDown   int
+		wantOK     bool
+	}{
+		{name: "up", prev: 100, last: 110, returnType: returnSimple, want: 0.1, wantUp: 1, wantOK: true},
+		{name: "down", prev: 100, last: 90, returnType: returnSimple, want: -0.1, wantDown: 1, wantOK: true},
+		{name: "flat", prev: 100, last: 100, returnType: returnSimple, want: 0, wantOK: true},
+		{name: "log", prev: 100, last: 110, returnType: returnLog, want: math.Log(1.1), wantUp: 1, wantOK: true},
+		{name: "no price", prev: 100, last: 0},
+		{name: "no previous close", prev: 0, last: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &finance.Quote{RegularMarketPreviousClose: tt.prev, RegularMarketPrice: tt.last, RegularMarketTime: 1758816000}
+			res, ok := quoteResult(q, tt.returnType, 2)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %t, want %t", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(res.Return-tt.want) > 1e-12 || res.UpDays != tt.wantUp || res.DownDays != tt.wantDown || res.BarCount != 2 {
+				t.Errorf("got return %v up %d down %d bars %d", res.Return, res.UpDays, res.DownDays, res.BarCount)
+			}
+		})
+	}
+}
+
+func TestQuoteIneligible(t *testing.T) {
+	now := time.Now().In(cfg.Location)
+	latest := lastTradingDay(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.Location), now)
+	previous := tradingDaysBack(latest, 1)
+	tests := []struct {
+		name       string
+		start      time.Time
+		last       time.Time
+		fopts      FetchOptions
+		ineligible bool
+	}{
+		{name: "latest session", start: previous, last: latest},
+		{name: "prior-close baseline", start: latest, last: latest, fopts: FetchOptions{Baseline: baselinePriorClose}},
+		{name: "extra periods", start: previous, last: latest, fopts: FetchOptions{PeriodStarts: map[string]time.Time{"ytd": previous}}, ineligible: true},
+		{name: "vwap", start: previous, last: latest, fopts: FetchOptions{ReturnBasis: basisVWAP}, ineligible: true},
+		{name: "ended before the latest session", start: tradingDaysBack(previous, 1), last: previous, ineligible: true},
+		{name: "several sessions", start: tradingDaysBack(latest, 5), last: latest, ineligible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := quoteIneligible(tt.start, tt.last, tt.fopts); (reason != "") != tt.ineligible {
+				t.Errorf("quoteIneligible = %q, want ineligible %t", reason, tt.ineligible)
+			}
+		})
+	}
+}
+
+func TestFetchQuotesBatches(t *testing.T) {
+	tests := []struct {
+		tickers     int
+		wantBatches []int
+	}{
+		{tickers: 1, wantBatches: []int{1}},
+		{tickers: quoteBatchSize, wantBatches: []int{quoteBatchSize}},
+		{tickers: 2*quoteBatchSize + 7, wantBatches: []int{quoteBatchSize, quoteBatchSize, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.tickers), func(t *testing.T) {
+			f := newFakeRun(t)
+			var tickers []string
+			for i := range tt.tickers {
+				ticker := fmt.Sprintf("T%03d", i)
+				tickers = append(tickers, ticker)
+				if i != 0 { // T000 is missing from the response, left for the chart
+					f.quotes[ticker] = finance.Quote{RegularMarketPreviousClose: 100, RegularMarketPrice: 101}
+				}
+			}
+			got := fetchQuotes(context.Background(), tickers, FetchOptions{ReturnType: returnSimple})
+			if !slices.Equal(f.batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", f.batches, tt.wantBatches)
+			}
+			if len(got) != tt.tickers-1 {
+				t.Errorf("got %d quoted results, want %d", len(got), tt.tickers-1)
+			}
+			if _, ok := got["T000"]; ok {
+				t.Error("T000 was quoted though the response left it out")
+			}
+		})
+	}
+}
+
+// TestRunQuoteComplete serves a run ending at the latest session from quotes
+// and checks the results count as complete, like the chart's would
+func TestRunQuoteComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline string
+		trailing int
+	}{
+		{name: "first in window", baseline: baselineFirstInWindow, trailing: 1},
+		{name: "prior close", baseline: baselinePriorClose, trailing: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			now := time.Now().In(cfg.Location)
+			latest := lastTradingDay(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.Location), now)
+			if !latest.Equal(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.Location)) {
+				t.Skip("today isn't a trading day, so no window ends at the latest session")
+			}
+			for _, ticker := range []string{"AAA", "BBB"} {
+				f.quotes[ticker] = finance.Quote{
+					Symbol:                     ticker,
+					RegularMarketPreviousClose: 100,
+					RegularMarketPrice:         101,
+					RegularMarketTime:          int(latest.Add(10 * time.Hour).Unix()),
+				}
+			}
+			var u Universe
+			u.add("AAA", "Tech", "")
+			u.add("BBB", "Tech", "")
+			opts := RunOptions{
+				Source:    func() (Universe, error) { return u, nil },
+				FetchMode: fetchQuote,
+				Baseline:  tt.baseline,
+				// Two sessions through the latest, or a window starting on it
+				TrailingDays: tt.trailing,
+				Fresh:        true,
+			}
+			results, summary, err := getMTDResults(latest.Year(), latest.Month(), latest.Day(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.FetchMode != fetchQuote {
+				t.Fatalf("fetch mode = %s, want quote", summary.FetchMode)
+			}
+			if len(results) != 2 {
+				t.Fatalf("%d results, want 2", len(results))
+			}
+			for _, r := range results {
+				if !r.Complete || r.BarCount != r.ExpectedBars {
+					t.Errorf("%s: complete = %t with %d of %d bars", r.Ticker, r.Complete, r.BarCount, r.ExpectedBars)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	rtdebug "runtime/debug"
+)
+
+// recoverMiddleware turns a panic in any handler into a 500 JSON error
+// instead of dropping the connection
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Printf("💥 Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, rtdebug.Stack())
+				writeJSONError(w, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverAsError converts a panic into *err so one bad item can't crash the process.
+// It must be deferred directly.
+func recoverAsError(err *error, item any) {
+	if p := recover(); p != nil {
+		log.Printf("💥 Panic processing %v: %v\n%s", item, p, rtdebug.Stack())
+		*err = fmt.Errorf("panic: %v", p)
+	}
+}
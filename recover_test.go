@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "no panic",
+			handler:  func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "panic value",
+			handler:  func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"internal server error"}`,
+		},
+		{
+			name: "nil map write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var m map[string]int
+				m["x"]++
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"internal server error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			recoverMiddleware(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantCode || strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRecoverMiddlewareAbort(t *testing.T) {
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler re-panicked", p)
+		}
+	}()
+	handler := func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }
+	recoverMiddleware(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestFetchPanicBecomesTickerError(t *testing.T) {
+	f := newFakeRun(t)
+	f.charts["AAA"] = laborDayBars
+	f.charts["BBB"] = laborDayBars
+	f.queue("BBB", errPanic)
+
+	results, summary, err := runFake(t, []string{"AAA", "BBB"}, RunOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Ticker != "AAA" {
+		t.Errorf("results = %+v, want only AAA", results)
+	}
+	if len(summary.Errors) != 1 || summary.Errors[0].Ticker != "BBB" || !strings.Contains(summary.Errors[0].Message, "panic") {
+		t.Errorf("errors = %+v, want BBB's panic", summary.Errors)
+	}
+}
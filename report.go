@@ -0,0 +1,55 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"time"
+)
+
+// reportTemplate is embedded so CLI reports work from any directory
+//
+//go:embed templates/report.html
+var reportTemplate string
+
+// reportData is the data rendered by the report template
+type reportData struct {
+	Title     string
+	CSS       template.CSS // Inlined so the report is a single self-contained file
+	Summary   RunSummary
+	Sectors   []SectorReturn
+	Results   []Result
+	Generated time.Time
+}
+
+// writeHTMLReport renders the results and sector tables to a standalone HTML file
+func writeHTMLReport(path string, results []Result, summary RunSummary) error {
+	tmpl, err := template.New("report.html").Funcs(templateFuncs).Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %v", err)
+	}
+	css, err := staticFiles.ReadFile("static/style.css")
+	if err != nil {
+		return fmt.Errorf("failed to read report CSS: %v", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %v", err)
+	}
+	defer f.Close()
+
+	data := reportData{
+		Title:     fmt.Sprintf("Returns %s to %s", summary.Start.Format("2006-01-02"), summary.End.Format("2006-01-02")),
+		CSS:       template.CSS(css),
+		Summary:   summary,
+		Sectors:   calculateSectorReturns(results),
+		Results:   results,
+		Generated: time.Now(),
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to render report: %v", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/piquette/finance-go"
+)
+
+// retryBackoff is the wait before a ticker's first retry; it doubles on each further retry
+const retryBackoff = time.Second
+
+// retryJitter randomizes retry waits so workers throttled together don't
+// retry in lockstep. It guards the run's *rand.Rand, which isn't safe for
+// concurrent use; a seeded one makes the waits reproducible.
+type retryJitter struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// backoff returns the wait before retry attempt (0 for the first): a random
+// duration between half and all of retryBackoff doubled per attempt
+func (j *retryJitter) backoff(attempt int) time.Duration {
+	d := retryBackoff << attempt
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return d/2 + time.Duration(j.rng.Int64N(int64(d/2)+1))
+}
+
+// retryBudget caps the retries of a whole run, shared by all workers, so an
+// outage can't multiply the request volume by the per-ticker retry count
+type retryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// take claims one retry from the budget, reporting false once it is exhausted
+func (b *retryBudget) take() bool {
+	for {
+		used := b.used.Load()
+		if used >= b.limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+1) {
+			return true
+		}
+	}
+}
+
+// retryable reports whether a fetch error is likely transient: rate limiting,
+// a Yahoo server error, or a network failure
+func retryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var remote *finance.RemoteError
+	if errors.As(err, &remote) {
+		return remote.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
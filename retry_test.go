@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	finance "github.com/piquette/finance-go"
+)
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		limit, takers int
+		want          int64
+	}{
+		{limit: 0, takers: 10, want: 0},
+		{limit: 1, takers: 10, want: 1},
+		{limit: 5, takers: 100, want: 5},
+		{limit: 50, takers: 20, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			b := &retryBudget{limit: int64(tt.limit)}
+			var granted atomic.Int64
+			var wg sync.WaitGroup
+			for range tt.takers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if b.take() {
+						granted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if granted.Load() != tt.want || b.used.Load() != tt.want {
+				t.Errorf("granted %d (used %d), want %d", granted.Load(), b.used.Load(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 0, min: retryBackoff / 2, max: retryBackoff},
+		{attempt: 1, min: retryBackoff, max: 2 * retryBackoff},
+		{attempt: 3, min: 4 * retryBackoff, max: 8 * retryBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt %d", tt.attempt), func(t *testing.T) {
+			rng, _ := newRunRand(7)
+			j := &retryJitter{rng: rng}
+			for range 100 {
+				if d := j.backoff(tt.attempt); d < tt.min || d > tt.max {
+					t.Fatalf("backoff = %v, want within [%v, %v]", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+
+	// The same seed replays the same waits
+	waits := func(seed uint64) []time.Duration {
+		rng, _ := newRunRand(seed)
+		j := &retryJitter{rng: rng}
+		var out []time.Duration
+		for attempt := range 5 {
+			out = append(out, j.backoff(attempt))
+		}
+		return out
+	}
+	if a, b := waits(42), waits(42); fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("seed 42 gave %v then %v", a, b)
+	}
+	if a, b := waits(42), waits(43); fmt.Sprint(a) == fmt.Sprint(b) {
+		t.Errorf("seeds 42 and 43 both gave %v", a)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "rate limited", err: fmt.Errorf("%w: 429", ErrRateLimited), want: true},
+		{name: "server error", err: &finance.RemoteError{StatusCode: 503}, want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "not found", err: fmt.Errorf("%w: 404", ErrSymbolNotFound), want: false},
+		{name: "client error", err: &finance.RemoteError{StatusCode: 400}, want: false},
+		{name: "no data", err: ErrNoData, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRetryBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        int
+		passAttempts  int
+		inline        int
+		wantUsed      int
+		wantRecovered int
+	}{
+		{name: "retry pass within budget", budget: 5, passAttempts: 1, wantUsed: 3, wantRecovered: 3},
+		{name: "retry pass over budget", budget: 1, passAttempts: 1, wantUsed: 1, wantRecovered: 1},
+		{name: "inline retries over budget", budget: 2, inline: 2, wantUsed: 2, wantRecovered: 2},
+		{name: "no budget", budget: 0, passAttempts: 1, wantUsed: 0, wantRecovered: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			withConfig(t, func(c *Config) {
+				c.RetryBudget, c.RetryPassAttempts, c.FetchRetries = tt.budget, tt.passAttempts, tt.inline
+			})
+			tickers := []string{"AAA", "BBB", "CCC"}
+			for _, ticker := range tickers {
+				f.charts[ticker] = laborDayBars
+				f.queue(ticker, &finance.RemoteError{StatusCode: 429}) // Throttled once
+			}
+
+			results, summary, err := runFake(t, tickers, RunOptions{Seed: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.RetriesUsed != tt.wantUsed || summary.RetryBudget != tt.budget {
+				t.Errorf("used %d of %d retries, want %d of %d", summary.RetriesUsed, summary.RetryBudget, tt.wantUsed, tt.budget)
+			}
+			if len(results) != tt.wantRecovered || summary.Failed != len(tickers)-tt.wantRecovered {
+				t.Errorf("%d results and %d failures, want %d recovered", len(results), summary.Failed, tt.wantRecovered)
+			}
+		})
+	}
+}
+
+func TestRunNoDataRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		retries       int
+		empty         int // Empty charts served before the data
+		unknown       bool
+		wantResult    bool
+		wantRetries   int
+		wantRecovered int
+	}{
+		{name: "retries off", retries: 0, empty: 1, wantRetries: 0},
+		{name: "empty then data", retries: 1, empty: 1, wantResult: true, wantRetries: 1, wantRecovered: 1},
+		{name: "empty twice then data", retries: 2, empty: 2, wantResult: true, wantRetries: 2, wantRecovered: 1},
+		{name: "empty past the retries", retries: 2, empty: 3, wantRetries: 2},
+		{name: "data first time", retries: 2, empty: 0, wantResult: true},
+		{name: "unknown symbol not retried", retries: 2, unknown: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			withConfig(t, func(c *Config) { c.NoDataRetries = tt.retries })
+			f.charts["GOOD"] = laborDayBars
+			if !tt.unknown {
+				f.charts["NEW"] = laborDayBars
+			}
+			for range tt.empty {
+				f.queue("NEW", errEmptyChart)
+			}
+
+			results, summary, err := runFake(t, []string{"GOOD", "NEW"}, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := slices.ContainsFunc(results, func(r Result) bool { return r.Ticker == "NEW" })
+			if got != tt.wantResult {
+				t.Errorf("NEW has a result = %t, want %t", got, tt.wantResult)
+			}
+			if summary.NoDataRetries != tt.wantRetries || summary.NoDataRecovered != tt.wantRecovered {
+				t.Errorf("%d no-data retries recovered %d, want %d recovering %d",
+					summary.NoDataRetries, summary.NoDataRecovered, tt.wantRetries, tt.wantRecovered)
+			}
+			if want := 1 + tt.wantRetries; f.chartCalls("NEW") != want {
+				t.Errorf("NEW fetched %d times, want %d", f.chartCalls("NEW"), want)
+			}
+			if summary.RetriesUsed != 0 {
+				t.Errorf("no-data retries spent %d of the retry budget", summary.RetriesUsed)
+			}
+		})
+	}
+}
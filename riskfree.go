@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// irxSymbol is the 13-week Treasury bill yield index, quoted in percent
+const irxSymbol = "^IRX"
+
+// riskFreeRate resolves the configured annualized risk-free rate.
+// The setting is either a decimal rate (e.g. "0.05") or "irx" to use the
+// latest ^IRX close in the window. It returns false when the feature is off.
+func riskFreeRate(setting string, start, end time.Time) (float64, bool, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return 0, false, nil
+	}
+
+	if strings.EqualFold(setting, "irx") {
+		res, err := getMTDReturn(context.Background(), irxSymbol, start, end, FetchOptions{})
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to fetch %s: %v", irxSymbol, err)
+		}
+		yield, _ := res.LastClose.Float64()
+		return yield / 100, true, nil
+	}
+
+	rate, err := strconv.ParseFloat(setting, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid risk-free rate %q: %v", setting, err)
+	}
+	return rate, true, nil
+}
+
+// prorateRate converts an annualized rate to the return over the window
+func prorateRate(annual float64, start, end time.Time) float64 {
+	days := end.Sub(start).Hours()/24 + 1
+	return annual * days / 365
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"time"
+)
+
+// rpcServiceName is the prefix of every JSON-RPC method (e.g. "Omaha.GetResults")
+const rpcServiceName = "Omaha"
+
+// RPCService exposes the server's data over JSON-RPC, backed by the same state as the HTTP API
+type RPCService struct {
+	server *Server
+}
+
+// RPCNoArgs is the argument type for methods that take no parameters
+type RPCNoArgs struct{}
+
+// GetResultsArgs filters the results returned by GetResults
+type GetResultsArgs struct {
+	Sector string // Optional sector name (case-insensitive); empty returns all results
+}
+
+// GetResultsReply is the reply of GetResults
+type GetResultsReply struct {
+	Results   []Result
+	Truncated bool // Results was cut to OMAHA_MAX_RESPONSE_RESULTS
+	Total     int  // Matching results before any cut
+}
+
+// RefreshArgs mirrors the /api/mtd query parameters
+type RefreshArgs struct {
+	Year, Month, Day int // Zero values default to the previous month
+	Index            string
+	Exclude          []string
+	Periods          []string
+	Fresh            bool
+}
+
+// GetResults returns the cached results, optionally limited to one sector, up
+// to the same cap as the HTTP list endpoints
+func (s *RPCService) GetResults(args *GetResultsArgs, reply *GetResultsReply) error {
+	s.server.mu.RLock()
+	defer s.server.mu.RUnlock()
+
+	var results []Result
+	for _, r := range s.server.results {
+		if args.Sector == "" || strings.EqualFold(r.Sector, args.Sector) {
+			results = append(results, r)
+		}
+	}
+	reply.Results, reply.Total = results, len(results)
+	if limit := cfg.MaxResponseResults; limit > 0 && len(results) > limit {
+		reply.Results, reply.Truncated = results[:limit], true
+	}
+	return nil
+}
+
+// GetSectors returns the sector aggregates for the cached results
+func (s *RPCService) GetSectors(_ *RPCNoArgs, reply *[]SectorReturn) error {
+	s.server.mu.RLock()
+	defer s.server.mu.RUnlock()
+
+	*reply = calculateSectorReturns(s.server.results)
+	return nil
+}
+
+// Refresh runs a new fetch, replaces the cached results, and returns the run summary
+func (s *RPCService) Refresh(args *RefreshArgs, reply *RunSummary) error {
+	if err := validateWindow(args.Year, args.Month, args.Day); err != nil {
+		return err
+	}
+	if args.Index != "" && !validIndex(args.Index) {
+		return fmt.Errorf("invalid index %q (expected %s)", args.Index, indexUsage)
+	}
+	periods, err := parsePeriods(strings.Join(args.Periods, ","))
+	if err != nil {
+		return err
+	}
+
+	opts := RunOptions{
+		Index:   args.Index,
+		Exclude: args.Exclude,
+		Periods: periods,
+		Fresh:   args.Fresh,
+	}
+	_, summary, err := s.server.refresh(args.Year, time.Month(args.Month), args.Day, opts)
+	if errors.Is(err, ErrRefreshBusy) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to refresh data: %v", err)
+	}
+
+	*reply = summary
+	return nil
+}
+
+// StartRPC serves the JSON-RPC interface on addr, one codec per TCP connection
+func (s *Server) StartRPC(addr string) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(rpcServiceName, &RPCService{server: s}); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("🔌 JSON-RPC server listening on %s\n", addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
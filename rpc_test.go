@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetResultsCap(t *testing.T) {
+	s := &Server{}
+	for i := range 10 {
+		s.results = append(s.results, Result{Ticker: fmt.Sprintf("T%d", i), Sector: []string{"Tech", "Energy"}[i%2]})
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		sector        string
+		wantResults   int
+		wantTotal     int
+		wantTruncated bool
+	}{
+		{name: "under the cap", limit: 20, wantResults: 10, wantTotal: 10},
+		{name: "over the cap", limit: 3, wantResults: 3, wantTotal: 10, wantTruncated: true},
+		{name: "sector over the cap", limit: 3, sector: "tech", wantResults: 3, wantTotal: 5, wantTruncated: true},
+		{name: "sector under the cap", limit: 5, sector: "Energy", wantResults: 5, wantTotal: 5},
+		{name: "cap disabled", limit: 0, wantResults: 10, wantTotal: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.MaxResponseResults = tt.limit })
+			var reply GetResultsReply
+			if err := (&RPCService{server: s}).GetResults(&GetResultsArgs{Sector: tt.sector}, &reply); err != nil {
+				t.Fatal(err)
+			}
+			if len(reply.Results) != tt.wantResults || reply.Total != tt.wantTotal || reply.Truncated != tt.wantTruncated {
+				t.Errorf("got %d of %d, truncated %t; want %d of %d, truncated %t",
+					len(reply.Results), reply.Total, reply.Truncated, tt.wantResults, tt.wantTotal, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestRPCRefreshWindow(t *testing.T) {
+	f := newFakeRun(t)
+	f.charts["AAA"] = dailyBars("2024-02-01", "2024-04-30", func(i int) float64 { return 100 + float64(i) })
+	writeTemplates(t, nil)
+	s := NewServer()
+
+	// Serve the S&P 500 universe from the ticker cache rather than Wikipedia
+	sp500Cache.mu.Lock()
+	saved := sp500Cache.universe
+	sp500Cache.universe, sp500Cache.fetched = Universe{}, time.Now()
+	sp500Cache.universe.add("AAA", "Tech", "")
+	sp500Cache.mu.Unlock()
+	t.Cleanup(func() {
+		sp500Cache.mu.Lock()
+		sp500Cache.universe, sp500Cache.fetched = saved, time.Time{}
+		sp500Cache.mu.Unlock()
+	})
+
+	tests := []struct {
+		name               string
+		args               RefreshArgs
+		wantStart, wantEnd string
+		wantErr            bool
+	}{
+		{name: "day omitted", args: RefreshArgs{Year: 2024, Month: 3}, wantStart: "2024-03-01", wantEnd: "2024-03-31"},
+		{name: "day given", args: RefreshArgs{Year: 2024, Month: 3, Day: 15}, wantStart: "2024-03-15", wantEnd: "2024-04-14"},
+		{name: "leap day", args: RefreshArgs{Year: 2024, Month: 2, Day: 29}, wantStart: "2024-02-29", wantEnd: "2024-03-28"},
+		{name: "invalid month", args: RefreshArgs{Year: 2024, Month: 13}, wantErr: true},
+		{name: "day without a month", args: RefreshArgs{Day: 5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reply RunSummary
+			err := (&RPCService{server: s}).Refresh(&tt.args, &reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Refresh error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			start, end := reply.Start.Format("2006-01-02"), reply.End.Format("2006-01-02")
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("window %s to %s, want %s to %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runKey identifies a run by its universe and requested window
+type runKey struct {
+	index      string
+	start, end time.Time
+}
+
+// newRunKey returns the key of a run over index from start to end
+func newRunKey(index string, start, end time.Time) runKey {
+	if index == "" {
+		index = indexSP500
+	}
+	return runKey{index: strings.ToLower(index), start: start, end: end}
+}
+
+// summaryRunKey returns the key of the run summary describes, using the
+// requested end when the run clamped it
+func summaryRunKey(summary RunSummary) runKey {
+	end := summary.End
+	if summary.RequestedEnd != nil {
+		end = *summary.RequestedEnd
+	}
+	return newRunKey(summary.Index, summary.Start, end)
+}
+
+// defaultRunOptions reports whether opts leave every setting that changes the
+// results at its configured default. /api/results?year=&month= asks the cache
+// for the window's default run, so a run with a limit, exclusions, a clamped
+// end, another return type and so on must not be cached under the same key.
+func defaultRunOptions(opts RunOptions) bool {
+	return opts.Source == nil && len(opts.Exclude) == 0 && len(opts.Periods) == 0 &&
+		opts.TrailingDays == 0 && opts.Limit == 0 && opts.Sample == 0 &&
+		opts.MinVolume == 0 && opts.MinPrice == 0 && !opts.Annualize && !opts.ClampEnd &&
+		(opts.ForwardFill == nil || *opts.ForwardFill == cfg.ForwardFill) &&
+		(opts.ReturnType == "" || opts.ReturnType == returnSimple) &&
+		(opts.ReturnBasis == "" || opts.ReturnBasis == basisClose) &&
+		(opts.FetchMode == "" || opts.FetchMode == fetchChart) &&
+		(opts.Baseline == "" || opts.Baseline == cfg.Baseline) &&
+		(opts.Weighting == "" || opts.Weighting == cfg.Weighting) &&
+		(opts.Metrics == "" || opts.Metrics == cfg.Metrics) &&
+		(opts.TargetCurrency == "" || strings.EqualFold(opts.TargetCurrency, cfg.TargetCurrency))
+}
+
+// runCache keeps the most recently used runs by key, evicting the least
+// recently used beyond its capacity
+type runCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used *list.Element of storedRun
+	entries  map[runKey]*list.Element
+}
+
+// newRunCache returns a cache holding up to capacity runs (0 disables it)
+func newRunCache(capacity int) *runCache {
+	return &runCache{capacity: capacity, order: list.New(), entries: make(map[runKey]*list.Element)}
+}
+
+// get returns the cached run for key, marking it most recently used
+func (c *runCache) get(key runKey) (storedRun, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return storedRun{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(storedRun), true
+}
+
+// put caches run under its key, replacing any earlier run for the same key
+func (c *runCache) put(run storedRun) {
+	if c.capacity <= 0 {
+		return
+	}
+	key := summaryRunKey(run.Summary)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = run
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(run)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, summaryRunKey(oldest.Value.(storedRun).Summary))
+	}
+}
+
+// clear drops every cached run, returning how many there were
+func (c *runCache) clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.order.Len()
+	c.order.Init()
+	clear(c.entries)
+	return n
+}
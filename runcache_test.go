@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestDefaultRunOptions(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name string
+		opts RunOptions
+		want bool
+	}{
+		{name: "zero options", want: true},
+		{name: "configured baseline", opts: RunOptions{Baseline: baselineFirstInWindow}, want: true},
+		{name: "fill matching the config", opts: RunOptions{ForwardFill: &off}, want: true},
+		{name: "fill differing from the config", opts: RunOptions{ForwardFill: &on}},
+		{name: "clamped end", opts: RunOptions{ClampEnd: true}},
+		{name: "limit", opts: RunOptions{Limit: 5}},
+		{name: "annualized", opts: RunOptions{Annualize: true}},
+		{name: "log returns", opts: RunOptions{ReturnType: returnLog}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.ForwardFill = false; c.Baseline = baselineFirstInWindow })
+			if got := defaultRunOptions(tt.opts); got != tt.want {
+				t.Errorf("defaultRunOptions(%+v) = %v, want %v", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
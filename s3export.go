@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3Exporter uploads artifacts to an S3-compatible bucket
+type s3Exporter struct {
+	client *minio.Client
+	bucket string
+}
+
+// newS3Exporter connects to the bucket configured in c. Credentials come from
+// c when set, otherwise from the standard AWS environment variables.
+func newS3Exporter(c Config) (*s3Exporter, error) {
+	creds := credentials.NewEnvAWS()
+	if c.S3AccessKey != "" {
+		creds = credentials.NewStaticV4(c.S3AccessKey, c.S3SecretKey, "")
+	}
+	client, err := minio.New(c.S3Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !c.S3Insecure,
+		Region: c.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %v", c.S3Endpoint, err)
+	}
+	return &s3Exporter{client: client, bucket: c.S3Bucket}, nil
+}
+
+// Put uploads body to key in the bucket
+func (e *s3Exporter) Put(ctx context.Context, key, contentType string, body []byte) error {
+	_, err := e.client.PutObject(ctx, e.bucket, key, bytes.NewReader(body), int64(len(body)),
+		minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// newRunRand returns the source of a run's randomness (its sample and retry
+// jitter) and the seed it was built from. A zero seed picks one from the
+// clock; passing a run's reported seed back reproduces it.
+func newRunRand(seed uint64) (*rand.Rand, uint64) {
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return rand.New(rand.NewPCG(seed, seed)), seed
+}
+
+// sampleUniverse picks n tickers from u, keeping their original order. A
+// stratified sample allocates tickers to each sector in proportion to its
+// size (at least one per sector while n allows); otherwise the pick is
+// uniformly random, drawn from rng.
+func sampleUniverse(u Universe, n int, stratified bool, rng *rand.Rand) Universe {
+	if n <= 0 || n >= u.Len() {
+		return u
+	}
+
+	var picked []int
+	if !stratified {
+		picked = rng.Perm(u.Len())[:n]
+	} else {
+		bySector := make(map[string][]int)
+		var sectors []string
+		for i, sector := range u.Sectors {
+			if _, ok := bySector[sector]; !ok {
+				sectors = append(sectors, sector)
+			}
+			bySector[sector] = append(bySector[sector], i)
+		}
+
+		// Largest sectors first, so rounding leftovers go to them
+		sort.SliceStable(sectors, func(i, j int) bool {
+			return len(bySector[sectors[i]]) > len(bySector[sectors[j]])
+		})
+		remaining := n
+		quotas := make([]int, len(sectors))
+		for k, sector := range sectors {
+			members := bySector[sector]
+			quota := int(math.Round(float64(n) * float64(len(members)) / float64(u.Len())))
+			if quota == 0 {
+				quota = 1
+			}
+			// Leave at least one for each sector still to come, when possible
+			if rest := len(sectors) - k - 1; quota > remaining-rest && remaining > rest {
+				quota = remaining - rest
+			}
+			quotas[k] = min(quota, remaining, len(members))
+			remaining -= quotas[k]
+		}
+		// Rounding down can leave the sample short; top up the largest sectors first
+		for remaining > 0 {
+			for k, sector := range sectors {
+				if remaining > 0 && quotas[k] < len(bySector[sector]) {
+					quotas[k]++
+					remaining--
+				}
+			}
+		}
+		for k, sector := range sectors {
+			members := bySector[sector]
+			for _, p := range rng.Perm(len(members))[:quotas[k]] {
+				picked = append(picked, members[p])
+			}
+		}
+	}
+
+	sort.Ints(picked)
+	sampled := Universe{Sources: u.Sources}
+	for _, i := range picked {
+		sampled.add(u.Tickers[i], u.Sectors[i], u.Names[i])
+	}
+	return sampled
+}
+
+// weightedIndexReturn extrapolates an index return from a stratified sample:
+// each sector's mean sample return is weighted by the sector's share of the
+// full universe. Sectors with no results are left out and the weights renormalized.
+func weightedIndexReturn(results []Result, population map[string]int) float64 {
+	sum := make(map[string]float64)
+	count := make(map[string]int)
+	for _, r := range results {
+		sum[r.Sector] += r.Return
+		count[r.Sector]++
+	}
+
+	total, weights := 0.0, 0
+	for sector, c := range count {
+		w := population[sector]
+		total += float64(w) * sum[sector] / float64(c)
+		weights += w
+	}
+	if weights == 0 {
+		return math.NaN()
+	}
+	return total / float64(weights)
+}
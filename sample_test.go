@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewRunRand(t *testing.T) {
+	tests := []struct {
+		name string
+		seed uint64
+	}{
+		{name: "explicit seed", seed: 42},
+		{name: "clock seed", seed: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng, seed := newRunRand(tt.seed)
+			if seed == 0 || (tt.seed != 0 && seed != tt.seed) {
+				t.Fatalf("seed = %d for %d", seed, tt.seed)
+			}
+			// The reported seed replays the same draws
+			replay, _ := newRunRand(seed)
+			for range 10 {
+				if a, b := rng.Uint64(), replay.Uint64(); a != b {
+					t.Fatalf("seed %d drew %d then %d", seed, a, b)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleUniverseSeeded(t *testing.T) {
+	var u Universe
+	for i := range 60 {
+		u.add(fmt.Sprintf("T%02d", i), []string{"Tech", "Energy", "Utilities"}[i%3], "")
+	}
+	sample := func(seed uint64, stratified bool) []string {
+		rng, _ := newRunRand(seed)
+		return sampleUniverse(u, 10, stratified, rng).Tickers
+	}
+	for _, stratified := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stratified=%t", stratified), func(t *testing.T) {
+			a, b := sample(7, stratified), sample(7, stratified)
+			if !slices.Equal(a, b) {
+				t.Errorf("seed 7 drew %v then %v", a, b)
+			}
+			if len(a) != 10 || !slices.IsSorted(a) {
+				t.Errorf("sample %v, want 10 tickers in universe order", a)
+			}
+			if c := sample(8, stratified); slices.Equal(a, c) {
+				t.Errorf("seeds 7 and 8 both drew %v", a)
+			}
+		})
+	}
+}
+
+func TestRunSeedReproducesSample(t *testing.T) {
+	f := newFakeRun(t)
+	var tickers []string
+	for i := range 30 {
+		ticker := fmt.Sprintf("T%02d", i)
+		tickers = append(tickers, ticker)
+		f.charts[ticker] = dailyBars("2025-09-01", "2025-09-30", func(d int) float64 { return 100 + float64(d+i) })
+	}
+	sampled := func(seed uint64) ([]string, uint64) {
+		results, summary, err := runFake(t, tickers, RunOptions{Sample: 5, Seed: seed, Fresh: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Ticker)
+		}
+		slices.Sort(got)
+		return got, summary.Seed
+	}
+
+	first, seed := sampled(0)
+	if seed == 0 {
+		t.Fatal("run reported no seed")
+	}
+	again, replayed := sampled(seed)
+	if replayed != seed || !slices.Equal(first, again) {
+		t.Errorf("seed %d sampled %v, then %v with seed %d", seed, first, again, replayed)
+	}
+}
+
+func TestRunStratifiedReturn(t *testing.T) {
+	// Seven Tech tickers gain 10% and one Energy ticker 30%; a stratified
+	// sample of four draws three Tech and the Energy ticker
+	var u Universe
+	for i := range 7 {
+		u.add(fmt.Sprintf("T%02d", i), "Tech", "")
+	}
+	u.add("E00", "Energy", "")
+	gain := func(pct float64) func(int) float64 {
+		return func(i int) float64 {
+			if i == 0 {
+				return 100
+			}
+			return 100 + pct
+		}
+	}
+	tests := []struct {
+		name           string
+		stratify       bool
+		wantIndex      float64
+		wantStratified float64
+	}{
+		{name: "stratified", stratify: true, wantIndex: 0.15, wantStratified: 0.125},
+		{name: "uniform", stratify: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			for i, ticker := range u.Tickers {
+				f.charts[ticker] = dailyBars("2025-09-01", "2025-09-30", gain(map[string]float64{"Tech": 10, "Energy": 30}[u.Sectors[i]]))
+			}
+			results, summary, err := getMTDResults(2025, time.September, 1, RunOptions{
+				Source:   func() (Universe, error) { return u, nil },
+				Now:      func() time.Time { return time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC) },
+				Sample:   4,
+				Stratify: tt.stratify,
+				Seed:     7,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 4 {
+				t.Fatalf("%d results, want the 4 sampled", len(results))
+			}
+			if !tt.stratify {
+				if summary.StratifiedReturn != nil {
+					t.Errorf("stratified return = %v for a uniform sample", *summary.StratifiedReturn)
+				}
+				return
+			}
+			if math.Abs(summary.IndexReturn-tt.wantIndex) > 1e-12 {
+				t.Errorf("index return = %v, want the sample mean %v", summary.IndexReturn, tt.wantIndex)
+			}
+			if r := summary.StratifiedReturn; r == nil || math.Abs(*r-tt.wantStratified) > 1e-12 {
+				t.Errorf("stratified return = %v, want %v", r, tt.wantStratified)
+			}
+		})
+	}
+}
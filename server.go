@@ -1,58 +1,191 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Server holds the web server state
 type Server struct {
 	templates map[string]*template.Template
+	tmplMu    sync.RWMutex // Guards templates, which may be reloaded while serving
 	results   []Result
+	summary   RunSummary // Summary of the run that produced results
 	mu        sync.RWMutex
+	events    *EventBus // Notifies subscribers when results change
+	updatedAt time.Time // When results were last replaced
+
+	// refreshSlots admits one running and one queued refresh; refreshMu runs them one at a time
+	refreshSlots chan struct{}
+	refreshMu    sync.Mutex
+	progress     RunProgress // Counters of the running refresh, for /api/progress
+
+	// The run replaced by the latest refresh, kept for ?changedSince=
+	previous          map[string]Result
+	previousRunID     string
+	previousUpdatedAt time.Time
+
+	history []storedRun // Recent runs for /api/diff, oldest first
+	cache   *runCache   // Recent runs by index and window, for /api/results?year=&month=
+
+	ready atomic.Bool // Set when a refresh stores its results and cleared by a reset, for /readyz
 }
 
 // NewServer creates a new server instance
 func NewServer() *Server {
 	s := &Server{
-		templates: make(map[string]*template.Template),
+		events:       NewEventBus(),
+		refreshSlots: make(chan struct{}, maxPendingRefreshes),
+		cache:        newRunCache(cfg.ResultsCacheSize),
 	}
 	s.loadTemplates()
 	return s
 }
 
-// loadTemplates loads all HTML templates
+// templateFuncs are the helpers available to every HTML template
+var templateFuncs = template.FuncMap{
+	"mult": func(a float64, b float64) float64 { return a * b },
+	// percent formats a fractional return as a localized percentage
+	"percent": func(v float64) string { return formatNumber("%.2f%%", v*100) },
+	// sign returns a CSS class for coloring a return
+	"sign": func(v float64) string {
+		switch {
+		case v > 0:
+			return "positive"
+		case v < 0:
+			return "negative"
+		}
+		return ""
+	},
+}
+
+// loadTemplates loads all HTML templates and swaps them in under the template lock
 func (s *Server) loadTemplates() {
 	templateFiles, err := filepath.Glob("templates/*.html")
 	if err != nil {
 		log.Fatalf("Failed to load templates: %v", err)
 	}
 
-	funcMap := template.FuncMap{
-		"mult": func(a float64, b float64) float64 { return a * b },
-	}
-
+	templates := make(map[string]*template.Template, len(templateFiles))
 	for _, tmpl := range templateFiles {
-		t, err := template.New(filepath.Base(tmpl)).Funcs(funcMap).ParseFiles(tmpl)
+		t, err := template.New(filepath.Base(tmpl)).Funcs(templateFuncs).ParseFiles(tmpl)
 		if err != nil {
 			log.Fatalf("Error parsing template %s: %v", tmpl, err)
 		}
-		s.templates[filepath.Base(tmpl)] = t
+		templates[filepath.Base(tmpl)] = t
+	}
+
+	s.tmplMu.Lock()
+	s.templates = templates
+	s.tmplMu.Unlock()
+}
+
+// getTemplate returns the named template in a thread-safe way
+func (s *Server) getTemplate(name string) (*template.Template, bool) {
+	s.tmplMu.RLock()
+	defer s.tmplMu.RUnlock()
+	t, ok := s.templates[name]
+	return t, ok
+}
+
+// maxPendingRefreshes is the number of refreshes admitted at once: one running, one queued
+const maxPendingRefreshes = 2
+
+// ErrRefreshBusy is returned when a refresh is already running and another is queued
+var ErrRefreshBusy = errors.New("a refresh is already running and another is queued")
+
+// beginRefresh waits for any running refresh to finish and returns a function
+// that ends this one. It fails with ErrRefreshBusy instead of queueing a second waiter.
+func (s *Server) beginRefresh() (func(), error) {
+	select {
+	case s.refreshSlots <- struct{}{}:
+	default:
+		return nil, ErrRefreshBusy
 	}
+	s.refreshMu.Lock()
+	return func() {
+		s.refreshMu.Unlock()
+		<-s.refreshSlots
+	}, nil
 }
 
-// UpdateResults updates the stored results in a thread-safe way
-func (s *Server) UpdateResults(results []Result) {
+// refresh runs getMTDResults as the server's next refresh, streaming its progress,
+// and replaces the cached results with its outcome
+func (s *Server) refresh(year int, month time.Month, day int, opts RunOptions) ([]Result, RunSummary, error) {
+	done, err := s.beginRefresh()
+	if err != nil {
+		return nil, RunSummary{}, err
+	}
+	defer done()
+
+	opts.OnResult = s.publishResult
+	opts.Progress = &s.progress
+	results, summary, err := getMTDResults(year, month, day, opts)
+	if err != nil {
+		return nil, summary, err
+	}
+	s.UpdateResults(results, summary)
+	return results, summary, nil
+}
+
+// writeRefreshError writes the response for a failed refresh
+func writeRefreshError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrRefreshBusy):
+		w.Header().Set("Retry-After", "30")
+		writeJSONError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, ErrInvalidRange):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		http.Error(w, fmt.Sprintf("Failed to refresh data: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// UpdateResults updates the stored results and their run summary in a thread-safe way,
+// then publishes a results-updated event
+func (s *Server) UpdateResults(results []Result, summary RunSummary) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	if s.summary.RunID != "" {
+		s.previous = make(map[string]Result, len(s.results))
+		for _, r := range s.results {
+			s.previous[r.Ticker] = r
+		}
+		s.previousRunID, s.previousUpdatedAt = s.summary.RunID, s.updatedAt
+	}
 	s.results = results
+	s.summary = summary
+	s.updatedAt = time.Now()
+	run := storedRun{Summary: summary, Results: results, UpdatedAt: s.updatedAt}
+	s.recordRun(run)
+	s.mu.Unlock()
+	s.ready.Store(true)
+
+	// Only a default run answers /api/results for its window; samples are
+	// estimates, so they never do
+	if summary.defaultOptions && !summary.Sampled {
+		s.cache.put(run)
+	}
+
+	// Publish outside the lock so subscribers may read the server state
+	s.events.Publish(Event{Topic: topicResultsUpdated, Summary: summary, Results: results})
+}
+
+// publishResult announces a single ticker's result while a refresh is running
+func (s *Server) publishResult(r Result) {
+	s.events.Publish(Event{Topic: topicTickerResult, Results: []Result{r}})
 }
 
 // handleIndex renders the main page
@@ -60,7 +193,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	tmpl, ok := s.templates["index.html"]
+	tmpl, ok := s.getTemplate("index.html")
 	if !ok {
 		http.Error(w, "Template not found", http.StatusInternalServerError)
 		return
@@ -72,16 +205,337 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleAPI returns the results as JSON
+// Optional ?units=bps emits returns in basis points instead of fractions,
+// ?groupBy=sector nests the results under their sectors, and ?changedSince=
+// limits them to tickers that changed since an earlier run. With ?year= and
+// ?month= (and optionally ?day= and ?index=) it serves that window's run from
+// the cache, refreshing only when it isn't cached.
 func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	query := r.URL.Query()
+	units := query.Get("units")
+	if units == "" {
+		units = cfg.ReturnUnits
+	}
+	bps := normalizeUnits(units) == unitsBps
+
+	year, month, day, ok := parseWindowParams(w, query)
+	if !ok {
+		return
+	}
+
+	var results []Result
+	if year == 0 {
+		s.mu.RLock()
+		results = s.results
+		if since := query.Get("changedSince"); since != "" {
+			results = s.changedSince(since)
+		}
+		s.mu.RUnlock()
+	} else {
+		if since := query.Get("changedSince"); since != "" {
+			writeParamError(w, "changedSince", since, "no year, month or day")
+			return
+		}
+		index := query.Get("index")
+		if !validIndex(index) {
+			writeParamError(w, "index", index, indexUsage)
+			return
+		}
+		start, end := getMonthRange(year, month, day)
+		if run, ok := s.cache.get(newRunKey(index, start, end)); ok {
+			results = run.Results
+		} else {
+			var err error
+			if results, _, err = s.refresh(year, month, day, RunOptions{Index: index}); err != nil {
+				writeRefreshError(w, err)
+				return
+			}
+		}
+	}
+
+	var payload any
+	switch groupBy := query.Get("groupBy"); groupBy {
+	case "":
+		results, _ = capItems(w, results)
+		payload = results
+		if bps {
+			payload = toBpsResults(results)
+		}
+	case "sector":
+		// Group before capping so the sector stats cover every result
+		groups := capGroups(w, groupBySector(results))
+		payload = groups
+		if bps {
+			type bpsGroup struct {
+				Sector  SectorReturn `json:"sector"`
+				Results []bpsResult  `json:"results"`
+			}
+			bpsGroups := make([]bpsGroup, len(groups))
+			for i, g := range groups {
+				bpsGroups[i] = bpsGroup{Sector: g.Sector, Results: toBpsResults(g.Results)}
+			}
+			payload = bpsGroups
+		}
+	default:
+		writeParamError(w, "groupBy", groupBy, "sector")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(s.results); err != nil {
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// changedSince returns the results that changed after since, a run ID or an
+// RFC 3339 timestamp. Only the previous run is kept, so anything older (or
+// unrecognized) returns the full set. The caller must hold s.mu.
+func (s *Server) changedSince(since string) []Result {
+	base, known := s.previous, false
+	switch since {
+	case s.summary.RunID:
+		return []Result{}
+	case s.previousRunID:
+		known = true
+	default:
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			if !t.Before(s.updatedAt) {
+				return []Result{}
+			}
+			known = !t.Before(s.previousUpdatedAt)
+		}
+	}
+	if !known || base == nil {
+		return s.results
+	}
+
+	changed := []Result{}
+	for _, r := range s.results {
+		prev, ok := base[r.Ticker]
+		if !ok || math.Abs(r.Return-prev.Return) > cfg.ChangeTolerance {
+			changed = append(changed, r)
+		}
+	}
+	return changed
+}
+
+// handleSectors returns the aggregate returns of the cached results.
+// ?groupBy= selects the grouping: sector (default) or capTier.
+func (s *Server) handleSectors(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	results, index := s.results, s.summary.Index
+	s.mu.RUnlock()
+
+	var keyFunc func(Result) string
+	switch groupBy := r.URL.Query().Get("groupBy"); groupBy {
+	case "", "sector":
+		keyFunc = func(r Result) string { return r.Sector }
+	case "capTier":
+		keyFunc = capTierKey(index)
+	default:
+		writeParamError(w, "groupBy", groupBy, "sector or capTier")
+		return
+	}
+
+	groups := aggregateBy(results, keyFunc)
+	if groups == nil {
+		groups = []GroupReturn{}
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// handleSector returns the cached results for one sector (case-insensitive) with its aggregate stats
+func (s *Server) handleSector(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	s.mu.RLock()
+	var members []Result
+	for _, res := range s.results {
+		if strings.EqualFold(res.Sector, name) {
+			members = append(members, res)
+		}
+	}
+	s.mu.RUnlock()
+
+	groups := groupBySector(members)
+	if len(groups) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown sector %q", name))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, capGroups(w, groups)[0])
+}
+
+// tickerResponse is the JSON body returned for a single ticker
+type tickerResponse struct {
+	Result Result    `json:"result"`
+	Series []float64 `json:"series,omitempty"`
+}
+
+// handleTicker returns the cached result for one ticker. With ?series=true it
+// also fetches the cumulative return series over the cached run's window.
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+
+	s.mu.RLock()
+	var found *Result
+	for i := range s.results {
+		if s.results[i].Ticker == symbol {
+			res := s.results[i]
+			found = &res
+			break
+		}
+	}
+	start, end := s.summary.Start, s.summary.End
+	s.mu.RUnlock()
+
+	if found == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown ticker %q", symbol))
+		return
+	}
+
+	resp := tickerResponse{Result: *found}
+	query := r.URL.Query()
+	if query.Get("series") == "true" {
+		fopts := FetchOptions{Series: true, ForwardFill: query.Get("fill") == "true"}
+		mtd, err := getMTDReturn(r.Context(), symbol, start, end, fopts)
+		if err != nil {
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch series: %v", err))
+			return
+		}
+		resp.Series = mtd.Series
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleBars returns the daily OHLCV bars for a ticker. The window comes from
+// ?start= and ?end= (YYYY-MM-DD), defaulting to the cached run's window.
+func (s *Server) handleBars(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	if !validTicker(symbol) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid ticker %q", symbol))
+		return
+	}
+
+	s.mu.RLock()
+	start, end := s.summary.Start, s.summary.End
+	s.mu.RUnlock()
+
+	query := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"start", &start}, {"end", &end}} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", v, cfg.Location)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s %q (expected YYYY-MM-DD)", p.name, v))
+			return
+		}
+		*p.dst = t
+	}
+	if start.IsZero() || end.IsZero() {
+		writeJSONError(w, http.StatusBadRequest, "no window: pass start and end or refresh first")
+		return
+	}
+	if err := validateRange(start, end, time.Now()); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bars, err := getBars(r.Context(), symbol, start, end)
+	if errors.Is(err, ErrInvalidRange) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bars)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeJSONError writes a {"error": msg} JSON response with the given status code
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// Headers marking a list response cut to OMAHA_MAX_RESPONSE_RESULTS, with
+// the number of entries there were before the cut
+const (
+	headerTruncated = "X-Results-Truncated"
+	headerTotal     = "X-Results-Total"
+)
+
+// markTruncated sets the truncation headers for a list of total entries
+func markTruncated(w http.ResponseWriter, total int) {
+	w.Header().Set(headerTruncated, "true")
+	w.Header().Set(headerTotal, strconv.Itoa(total))
+}
+
+// capItems cuts items to cfg.MaxResponseResults, marking the response and
+// reporting true when it does. Callers keep items in their response order,
+// so the cut keeps the top of the list.
+func capItems[T any](w http.ResponseWriter, items []T) ([]T, bool) {
+	limit := cfg.MaxResponseResults
+	if limit <= 0 || len(items) <= limit {
+		return items, false
+	}
+	markTruncated(w, len(items))
+	return items[:limit], true
+}
+
+// capGroups is capItems across the results of sector groups: groups are
+// filled in order until the cap is reached, and later groups keep their
+// stats but list no results
+func capGroups(w http.ResponseWriter, groups []SectorGroup) []SectorGroup {
+	limit, total := cfg.MaxResponseResults, 0
+	for _, g := range groups {
+		total += len(g.Results)
+	}
+	if limit <= 0 || total <= limit {
+		return groups
+	}
+	markTruncated(w, total)
+	capped := make([]SectorGroup, len(groups))
+	for i, g := range groups {
+		n := min(len(g.Results), limit)
+		capped[i] = SectorGroup{Sector: g.Sector, Results: g.Results[:n]}
+		limit -= n
+	}
+	return capped
+}
+
+// paramError is the JSON body returned for an invalid query parameter
+type paramError struct {
+	Error string `json:"error"`
+	Param string `json:"param"`
+	Value string `json:"value"`
+}
+
+// writeParamError writes a 400 describing why a query parameter was rejected
+func writeParamError(w http.ResponseWriter, param, value, expected string) {
+	writeJSON(w, http.StatusBadRequest, paramError{
+		Error: fmt.Sprintf("invalid %s %q (expected %s)", param, value, expected),
+		Param: param,
+		Value: value,
+	})
+}
+
 // handleRefresh triggers a refresh of the MTD data
 func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	// if r.Method != http.MethodPost || r.Method != http.MethodGet {
@@ -89,39 +543,259 @@ func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	// 	return
 	// }
 
-	// Parse query parameters for year and month
 	query := r.URL.Query()
-	year := 0
-	month := time.Month(0)
-	day := 0
+	year, month, day, ok := parseWindowParams(w, query)
+	if !ok {
+		return
+	}
 
-	if y := query.Get("year"); y != "" {
-		if y, err := strconv.Atoi(y); err == nil && y > 0 {
-			year = y
-		}
+	periods, err := parsePeriods(query.Get("periods"))
+	if err != nil {
+		writeParamError(w, "periods", query.Get("periods"), "a comma-separated list of "+strings.Join(knownPeriods, ", "))
+		return
 	}
 
-	if m := query.Get("month"); m != "" {
-		if m, err := strconv.Atoi(m); err == nil && m >= 1 && m <= 12 {
-			month = time.Month(m)
-		}
+	index := query.Get("index")
+	if !validIndex(index) {
+		writeParamError(w, "index", index, indexUsage)
+		return
 	}
 
-	if d := query.Get("day"); d != "" {
-		if d, err := strconv.Atoi(d); err == nil && d >= 1 && d <= 31 {
-			day = d
+	opts := RunOptions{
+		Index:   index,
+		Exclude: splitList(query.Get("exclude")),
+		Units:   query.Get("units"),
+		Periods: periods,
+		Fresh:   query.Get("fresh") == "true",
+
+		ClampEnd:  query.Get("clampEnd") == "true",
+		Annualize: query.Get("annualize") == "true",
+	}
+	if fill := query.Get("fill"); fill != "" {
+		forwardFill := fill == "true"
+		opts.ForwardFill = &forwardFill
+	}
+	switch b := query.Get("baseline"); b {
+	case "", baselineFirstInWindow, baselinePriorClose, baselineNearest:
+		opts.Baseline = b
+	default:
+		writeParamError(w, "baseline", b, "first-in-window, prior-close or nearest")
+		return
+	}
+	switch rb := query.Get("returnBasis"); rb {
+	case "", basisClose, basisVWAP:
+		opts.ReturnBasis = rb
+	default:
+		writeParamError(w, "returnBasis", rb, "close or vwap")
+		return
+	}
+	switch fm := query.Get("fetchMode"); fm {
+	case "", fetchChart, fetchQuote:
+		opts.FetchMode = fm
+	default:
+		writeParamError(w, "fetchMode", fm, "chart or quote")
+		return
+	}
+	switch fo := query.Get("fetchOrder"); fo {
+	case "", fetchOrderScrape, fetchOrderAlphabetical, fetchOrderSector:
+		opts.FetchOrder = fo
+	default:
+		writeParamError(w, "fetchOrder", fo, "scrape, alphabetical or sector")
+		return
+	}
+	switch wt := query.Get("weighting"); wt {
+	case "", weightingEqual, weightingCustom:
+		opts.Weighting = wt
+	default:
+		writeParamError(w, "weighting", wt, "equal or custom")
+		return
+	}
+	if tc := query.Get("targetCurrency"); tc != "" {
+		if !validCurrency(strings.ToUpper(tc)) {
+			writeParamError(w, "targetCurrency", tc, "an ISO 4217 currency code such as EUR")
+			return
 		}
+		opts.TargetCurrency = tc
+	}
+	if m := query.Get("metrics"); m != "" {
+		if _, err := parseMetrics(m); err != nil {
+			writeParamError(w, "metrics", m, "a comma-separated list of volatility, max_drawdown and relative_to_sector, or none")
+			return
+		}
+		opts.Metrics = m
+	}
+	switch rt := query.Get("returnType"); rt {
+	case "", returnSimple, returnLog:
+		opts.ReturnType = rt
+	default:
+		writeParamError(w, "returnType", rt, "simple or log")
+		return
+	}
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			writeParamError(w, "limit", l, "a positive integer")
+			return
+		}
+		opts.Limit = n
+	}
+	if mv := query.Get("minVolume"); mv != "" {
+		v, err := strconv.ParseFloat(mv, 64)
+		if err != nil || v < 0 {
+			writeParamError(w, "minVolume", mv, "a non-negative number")
+			return
+		}
+		opts.MinVolume = v
+	}
+	if mp := query.Get("minPrice"); mp != "" {
+		v, err := strconv.ParseFloat(mp, 64)
+		if err != nil || v < 0 {
+			writeParamError(w, "minPrice", mp, "a non-negative number")
+			return
+		}
+		opts.MinPrice = v
+	}
+	if n := query.Get("sample"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 1 {
+			writeParamError(w, "sample", n, "a positive integer")
+			return
+		}
+		opts.Sample = v
+		opts.Stratify = query.Get("stratify") == "true"
+	}
+	if sd := query.Get("seed"); sd != "" {
+		v, err := strconv.ParseUint(sd, 10, 64)
+		if err != nil {
+			writeParamError(w, "seed", sd, "a non-negative integer")
+			return
+		}
+		opts.Seed = v
+	}
+	if td := query.Get("trailingDays"); td != "" {
+		n, err := strconv.Atoi(td)
+		if err != nil || n < 1 {
+			writeParamError(w, "trailingDays", td, "a positive integer")
+			return
+		}
+		opts.TrailingDays = n
+	}
+	if asOf := query.Get("asOf"); asOf != "" {
+		clock, err := fixedClock(asOf)
+		if err != nil {
+			writeParamError(w, "asOf", asOf, "YYYY-MM-DD")
+			return
+		}
+		opts.Now = clock
 	}
 
-	results, err := getMTDResults(year, month, day)
+	_, summary, err := s.refresh(year, month, day, opts)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to refresh data: %v", err), http.StatusInternalServerError)
+		writeRefreshError(w, err)
 		return
 	}
 
-	s.UpdateResults(results)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	json.NewEncoder(w).Encode(refreshResponse{Success: true, RunSummary: summary})
+}
+
+// refreshResponse is the JSON body returned by a successful refresh
+type refreshResponse struct {
+	Success bool `json:"success"`
+	RunSummary
+}
+
+// handleSummary returns the summary of the run behind the cached results
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	summary := s.summary
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// errorsResponse is the JSON body returned by /api/errors
+type errorsResponse struct {
+	Categories map[string]int `json:"categories"` // Failure count per category
+	Errors     []TickerError  `json:"errors"`
+	Truncated  bool           `json:"truncated,omitempty"` // Errors was cut to OMAHA_MAX_RESPONSE_RESULTS
+}
+
+// handleErrors returns the per-ticker failures of the run behind the cached results.
+// Optional ?category= limits them to one category.
+func (s *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	all := s.summary.Errors
+	s.mu.RUnlock()
+
+	category := r.URL.Query().Get("category")
+	resp := errorsResponse{Categories: make(map[string]int), Errors: []TickerError{}}
+	for _, e := range all {
+		resp.Categories[e.Category]++
+		if category == "" || e.Category == category {
+			resp.Errors = append(resp.Errors, e)
+		}
+	}
+	resp.Errors, resp.Truncated = capItems(w, resp.Errors)
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSuspect returns the results held out of the rankings for implausible returns
+func (s *Server) handleSuspect(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	suspect := s.summary.SuspectResults
+	s.mu.RUnlock()
+
+	if suspect == nil {
+		suspect = []Result{}
+	}
+	suspect, _ = capItems(w, suspect)
+	writeJSON(w, http.StatusOK, suspect)
+}
+
+// resetResponse confirms an /api/reset with what it cleared
+type resetResponse struct {
+	Reset          bool `json:"reset"`
+	ClearedResults int  `json:"cleared_results"`
+	ClearedErrors  int  `json:"cleared_errors"`
+	ClearedRuns    int  `json:"cleared_runs"`   // Runs dropped from the /api/diff history
+	ClearedCached  int  `json:"cleared_cached"` // Runs dropped from the results cache
+}
+
+// handleReset clears the cached results, their summary and errors, and the
+// run history, returning the server to its state before the first refresh.
+// With OMAHA_RESET_TOKEN set it requires "Authorization: Bearer <token>".
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST to reset")
+		return
+	}
+	if token := cfg.ResetToken; token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid reset token")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	resp := resetResponse{
+		Reset:          true,
+		ClearedResults: len(s.results),
+		ClearedErrors:  len(s.summary.Errors),
+		ClearedRuns:    len(s.history),
+	}
+	s.results, s.summary, s.updatedAt = nil, RunSummary{}, time.Time{}
+	s.previous, s.previousRunID, s.previousUpdatedAt = nil, "", time.Time{}
+	s.history = nil
+	s.mu.Unlock()
+	s.ready.Store(false) // Back to the empty state, so not ready until the next refresh
+	resp.ClearedCached = s.cache.clear()
+
+	log.Printf("🧹 Reset: cleared %d results, %d errors and %d runs\n", resp.ClearedResults, resp.ClearedErrors, resp.ClearedRuns)
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // Start starts the web server
@@ -131,10 +805,26 @@ func (s *Server) Start(addr string) error {
 	http.HandleFunc("/", s.handleIndex)
 	http.HandleFunc("/api/results", s.handleAPI)
 	http.HandleFunc("/api/mtd", s.handleRefresh)
+	http.HandleFunc("/api/sectors", s.handleSectors)
+	http.HandleFunc("/api/sector/{name}", s.handleSector)
+	http.HandleFunc("/api/ticker/{symbol}", s.handleTicker)
+	http.HandleFunc("/api/summary", s.handleSummary)
+	http.HandleFunc("/api/bars/{symbol}", s.handleBars)
+	http.HandleFunc("/api/errors", s.handleErrors)
+	http.HandleFunc("/api/suspect", s.handleSuspect)
+	http.HandleFunc("/api/stream", s.handleStream)
+	http.HandleFunc("/api/diff", s.handleDiff)
+	http.HandleFunc("/api/progress", s.handleProgress)
+	http.HandleFunc("/api/config", s.handleConfig)
+	http.HandleFunc("/api/reset", s.handleReset)
+	http.HandleFunc("/healthz", s.handleHealthz)
+	http.HandleFunc("/readyz", s.handleReadyz)
+	http.Handle("/static/", staticHandler())
 
 	// Start server
 	server := &http.Server{
 		Addr:         addr,
+		Handler:      recoverMiddleware(http.DefaultServeMux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -142,3 +832,82 @@ func (s *Server) Start(addr string) error {
 	log.Printf("🚀 Server starting on http://%s\n", addr)
 	return server.ListenAndServe()
 }
+
+// windowError is a window parameter rejected by validateWindow
+type windowError struct {
+	Param    string
+	Value    int
+	Expected string
+}
+
+func (e *windowError) Error() string {
+	return fmt.Sprintf("invalid %s %d (expected %s)", e.Param, e.Value, e.Expected)
+}
+
+// validateWindow checks a window given as numbers, for the HTTP parameters
+// and the JSON-RPC arguments alike. All zeros mean the previous month; a zero
+// day means the 1st. Otherwise year and month must both be given, and
+// nothing out of range is defaulted.
+func validateWindow(year, month, day int) error {
+	switch {
+	case year == 0 && month == 0 && day == 0:
+		return nil
+	case year == 0 && month != 0:
+		return &windowError{"year", year, "year and month to be given together"}
+	case month == 0:
+		return &windowError{"month", month, "year and month to be given together"}
+	case year < 1:
+		return &windowError{"year", year, "a positive integer"}
+	case month < 1 || month > 12:
+		return &windowError{"month", month, "1-12"}
+	}
+	if last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day(); day < 0 || day > last {
+		return &windowError{"day", day, fmt.Sprintf("1-%d", last)}
+	}
+	return nil
+}
+
+// parseWindowParams parses the ?year=, ?month= and ?day= window parameters,
+// writing a 400 and reporting false when they are invalid. Omitting all three
+// returns zeros, meaning the previous month; anything partial or out of range
+// is rejected rather than defaulted (see validateWindow).
+func parseWindowParams(w http.ResponseWriter, query url.Values) (int, time.Month, int, bool) {
+	y, m, d := query.Get("year"), query.Get("month"), query.Get("day")
+	if y == "" && m == "" && d == "" {
+		return 0, 0, 0, true
+	}
+	if y == "" || m == "" {
+		param := "year"
+		if y != "" {
+			param = "month"
+		}
+		writeParamError(w, param, "", "year and month to be given together")
+		return 0, 0, 0, false
+	}
+	// Given values are never zero, which validateWindow reads as absent
+	values := map[string]string{"year": y, "month": m, "day": d}
+	year, err := strconv.Atoi(y)
+	if err != nil || year == 0 {
+		year = -1
+	}
+	month, err := strconv.Atoi(m)
+	if err != nil || month == 0 {
+		month = -1
+	}
+	day := 1
+	if d != "" {
+		if day, err = strconv.Atoi(d); err != nil || day == 0 {
+			day = -1
+		}
+	}
+	if err := validateWindow(year, month, day); err != nil {
+		var we *windowError
+		if errors.As(err, &we) {
+			writeParamError(w, we.Param, values[we.Param], we.Expected)
+		} else {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return 0, 0, 0, false
+	}
+	return year, time.Month(month), day, true
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeTemplates creates templates/ under a fresh working directory with the given files
+func writeTemplates(t *testing.T, files map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("templates", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join("templates", name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHandleIndexTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "renders index",
+			files:    map[string]string{"index.html": `{{len .}} results`},
+			wantCode: http.StatusOK,
+			wantBody: "0 results",
+		},
+		{
+			name:     "missing index",
+			files:    map[string]string{"report.html": `report`},
+			wantCode: http.StatusInternalServerError,
+			wantBody: "Template not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTemplates(t, tt.files)
+			s := NewServer()
+			rec := httptest.NewRecorder()
+			s.handleIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %q, want %d containing %q", rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestTemplatesReloadWhileServing renders the index while the templates are
+// reloaded; run with -race to check getTemplate and loadTemplates don't race
+func TestTemplatesReloadWhileServing(t *testing.T) {
+	writeTemplates(t, map[string]string{"index.html": `ok`})
+	s := NewServer()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				s.loadTemplates()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				rec := httptest.NewRecorder()
+				s.handleIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("got %d during reload", rec.Code)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHandleRefreshRejectsFutureWindow(t *testing.T) {
+	writeTemplates(t, nil)
+	s := NewServer()
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "next year", query: "year=2099&month=1"},
+		{name: "as of before the window", query: "year=2025&month=9&asOf=2025-08-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleRefresh(rec, httptest.NewRequest(http.MethodGet, "/api/mtd?"+tt.query, nil))
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "in the future") {
+				t.Errorf("got %d %s, want 400 for a future window", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestParseWindowParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantYear  int
+		wantMonth time.Month
+		wantDay   int
+		wantParam string // Rejected parameter; empty when the window is valid
+	}{
+		{query: ""},
+		{query: "year=2025&month=9", wantYear: 2025, wantMonth: time.September, wantDay: 1},
+		{query: "year=2025&month=9&day=17", wantYear: 2025, wantMonth: time.September, wantDay: 17},
+		{query: "year=2024&month=2&day=29", wantYear: 2024, wantMonth: time.February, wantDay: 29},
+		{query: "year=2025&month=13", wantParam: "month"},
+		{query: "year=2025&month=0", wantParam: "month"},
+		{query: "year=2025&month=9&day=40", wantParam: "day"},
+		{query: "year=2025&month=2&day=29", wantParam: "day"},
+		{query: "year=2025&month=9&day=0", wantParam: "day"},
+		{query: "year=-1&month=9", wantParam: "year"},
+		{query: "year=abc&month=9", wantParam: "year"},
+		{query: "month=9", wantParam: "year"},
+		{query: "year=2025", wantParam: "month"},
+		{query: "day=5", wantParam: "year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			rec := httptest.NewRecorder()
+			year, month, day, ok := parseWindowParams(rec, q)
+			if tt.wantParam == "" {
+				if !ok || year != tt.wantYear || month != tt.wantMonth || day != tt.wantDay {
+					t.Errorf("got %d-%d-%d ok=%t, want %d-%d-%d", year, month, day, ok, tt.wantYear, tt.wantMonth, tt.wantDay)
+				}
+				return
+			}
+			var body paramError
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if ok || rec.Code != http.StatusBadRequest || body.Param != tt.wantParam || body.Value != q.Get(tt.wantParam) {
+				t.Errorf("got ok=%t %d %+v, want 400 for %s", ok, rec.Code, body, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	tests := []struct {
+		year, month, day int
+		wantParam        string
+	}{
+		{year: 0, month: 0, day: 0},
+		{year: 2025, month: 9, day: 0},
+		{year: 2025, month: 12, day: 31},
+		{year: 0, month: 9, day: 1, wantParam: "year"},
+		{year: 2025, month: 0, day: 1, wantParam: "month"},
+		{year: -1, month: 9, day: 1, wantParam: "year"},
+		{year: 2025, month: 13, day: 1, wantParam: "month"},
+		{year: 2025, month: 4, day: 31, wantParam: "day"},
+		{year: 2025, month: 9, day: -1, wantParam: "day"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d-%d-%d", tt.year, tt.month, tt.day), func(t *testing.T) {
+			err := validateWindow(tt.year, tt.month, tt.day)
+			var we *windowError
+			switch {
+			case tt.wantParam == "" && err != nil:
+				t.Errorf("validateWindow = %v, want nil", err)
+			case tt.wantParam != "" && (!errors.As(err, &we) || we.Param != tt.wantParam):
+				t.Errorf("validateWindow = %v, want an error for %s", err, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestRefreshQueue(t *testing.T) {
+	writeTemplates(t, nil)
+	s := NewServer()
+
+	// The first refresh runs and the second queues behind it
+	done1, err := s.beginRefresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := make(chan func())
+	go func() {
+		done2, err := s.beginRefresh()
+		if err != nil {
+			t.Error(err)
+			close(second)
+			return
+		}
+		second <- done2
+	}()
+	for len(s.refreshSlots) < maxPendingRefreshes {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Further refreshes are turned away while both slots are taken
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "beginRefresh", call: func() error { _, err := s.beginRefresh(); return err }},
+		{name: "handler", call: func() error {
+			rec := httptest.NewRecorder()
+			s.handleRefresh(rec, httptest.NewRequest(http.MethodGet, "/api/mtd?year=2025&month=9", nil))
+			if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+				return fmt.Errorf("got %d %s, want 429 with Retry-After", rec.Code, rec.Body)
+			}
+			return ErrRefreshBusy
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrRefreshBusy) {
+				t.Errorf("third refresh: %v, want ErrRefreshBusy", err)
+			}
+		})
+	}
+
+	// Finishing the first lets the queued one run, freeing a slot
+	done1()
+	done2, ok := <-second
+	if !ok {
+		t.Fatal("queued refresh failed")
+	}
+	if n := len(s.refreshSlots); n != 1 {
+		t.Errorf("%d slots taken with one refresh running, want 1", n)
+	}
+	done2()
+	done3, err := s.beginRefresh()
+	if err != nil {
+		t.Fatalf("refresh after both finished: %v", err)
+	}
+	done3()
+}
+
+func TestHandleRefreshBaselineParam(t *testing.T) {
+	writeTemplates(t, nil)
+	s := NewServer()
+	rec := httptest.NewRecorder()
+	s.handleRefresh(rec, httptest.NewRequest(http.MethodGet, "/api/mtd?year=2025&month=9&baseline=latest", nil))
+	var body paramError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Param != "baseline" || !strings.Contains(body.Error, baselineNearest) {
+		t.Errorf("got %d %+v, want 400 listing the baseline strategies", rec.Code, body)
+	}
+}
+
+func TestHandleRefreshMetricsParam(t *testing.T) {
+	writeTemplates(t, nil)
+	s := NewServer()
+	rec := httptest.NewRecorder()
+	s.handleRefresh(rec, httptest.NewRequest(http.MethodGet, "/api/mtd?year=2025&month=9&metrics=volatility,beta", nil))
+	var body paramError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Param != "metrics" || body.Value != "volatility,beta" {
+		t.Errorf("got %d %+v, want 400 for metrics", rec.Code, body)
+	}
+}
+
+func TestHandleAPIResponseCap(t *testing.T) {
+	writeTemplates(t, nil)
+	s := NewServer()
+	for i := range 12 {
+		sector := []string{"Tech", "Energy"}[i%2]
+		s.results = append(s.results, Result{Ticker: fmt.Sprintf("T%02d", i), Sector: sector, Return: float64(12-i) / 100})
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		query         string
+		wantResults   int
+		wantTruncated bool
+	}{
+		{name: "under the cap", limit: 20, wantResults: 12},
+		{name: "at the cap", limit: 12, wantResults: 12},
+		{name: "over the cap", limit: 5, wantResults: 5, wantTruncated: true},
+		{name: "cap disabled", limit: 0, wantResults: 12},
+		{name: "grouped over the cap", limit: 8, query: "groupBy=sector", wantResults: 8, wantTruncated: true},
+		{name: "grouped under the cap", limit: 20, query: "groupBy=sector", wantResults: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.MaxResponseResults = tt.limit })
+			rec := httptest.NewRecorder()
+			s.handleAPI(rec, httptest.NewRequest(http.MethodGet, "/api/results?"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %d %s", rec.Code, rec.Body)
+			}
+
+			got := 0
+			if tt.query == "" {
+				var results []Result
+				if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+					t.Fatal(err)
+				}
+				got = len(results)
+				if tt.wantTruncated && results[0].Ticker != "T00" {
+					t.Errorf("cut dropped the top of the list: first is %s", results[0].Ticker)
+				}
+			} else {
+				var groups []SectorGroup
+				if err := json.Unmarshal(rec.Body.Bytes(), &groups); err != nil {
+					t.Fatal(err)
+				}
+				for _, g := range groups {
+					got += len(g.Results)
+					if g.Sector.TickerCount != 6 {
+						t.Errorf("%s stats cover %d tickers, want all 6", g.Sector.Sector, g.Sector.TickerCount)
+					}
+				}
+			}
+			if got != tt.wantResults {
+				t.Errorf("%d results, want %d", got, tt.wantResults)
+			}
+
+			truncated := rec.Header().Get(headerTruncated) == "true"
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %t, want %t", truncated, tt.wantTruncated)
+			}
+			if total := rec.Header().Get(headerTotal); tt.wantTruncated && total != "12" {
+				t.Errorf("total header = %q, want 12", total)
+			}
+		})
+	}
+}
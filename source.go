@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Universe is a ticker universe kept as aligned slices:
+// Sectors[i] and Names[i] describe Tickers[i]
+type Universe struct {
+	Tickers []string
+	Sectors []string
+	Names   []string
+
+	Sources map[string][]string // Source indices per ticker, set for combined universes
+}
+
+// add appends a constituent, keeping the slices aligned
+func (u *Universe) add(ticker, sector, name string) {
+	u.Tickers = append(u.Tickers, ticker)
+	u.Sectors = append(u.Sectors, sector)
+	u.Names = append(u.Names, name)
+}
+
+// Len returns the number of tickers in the universe
+func (u Universe) Len() int {
+	return len(u.Tickers)
+}
+
+// aligned returns an error if the slices have drifted out of alignment
+func (u Universe) aligned() error {
+	if len(u.Sectors) != len(u.Tickers) || len(u.Names) != len(u.Tickers) {
+		return fmt.Errorf("universe has %d tickers but %d sectors and %d names",
+			len(u.Tickers), len(u.Sectors), len(u.Names))
+	}
+	return nil
+}
+
+// clone returns a copy that shares no backing arrays with u
+func (u Universe) clone() Universe {
+	return Universe{
+		Tickers: append([]string(nil), u.Tickers...),
+		Sectors: append([]string(nil), u.Sectors...),
+		Names:   append([]string(nil), u.Names...),
+		Sources: maps.Clone(u.Sources),
+	}
+}
+
+// Orders in which a run's tickers are fetched. The results are the same
+// either way; only the order they stream in and advance progress changes.
+const (
+	fetchOrderScrape       = "scrape"       // Default: the source's own order
+	fetchOrderAlphabetical = "alphabetical" // By ticker
+	fetchOrderSector       = "sector"       // Grouped by sector, alphabetical within each
+)
+
+// validFetchOrder reports whether s names a known fetch order
+func validFetchOrder(s string) bool {
+	switch s {
+	case "", fetchOrderScrape, fetchOrderAlphabetical, fetchOrderSector:
+		return true
+	}
+	return false
+}
+
+// orderUniverse returns u's constituents in the given fetch order
+func orderUniverse(u Universe, order string) Universe {
+	if order != fetchOrderAlphabetical && order != fetchOrderSector {
+		return u
+	}
+	idx := make([]int, u.Len())
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(i, j int) int {
+		if order == fetchOrderSector && u.Sectors[i] != u.Sectors[j] {
+			return strings.Compare(u.Sectors[i], u.Sectors[j])
+		}
+		return strings.Compare(u.Tickers[i], u.Tickers[j])
+	})
+	out := Universe{Sources: u.Sources}
+	for _, i := range idx {
+		out.add(u.Tickers[i], u.Sectors[i], u.Names[i])
+	}
+	return out
+}
+
+// TickerSource returns the ticker universe to fetch
+type TickerSource func() (Universe, error)
+
+// unknownSector is the bucket for tickers whose sector could not be determined
+const unknownSector = "Unknown"
+
+// stdinSector is the sector assigned to tickers read from a plain list
+const stdinSector = unknownSector
+
+// Strategies for tickers in the unknown sector
+const (
+	unknownKeep     = "keep"     // Keep them in sector aggregates (but out of the sector logs)
+	unknownDrop     = "drop"     // Leave them out of sector aggregates; they stay in the ticker list
+	unknownBackfill = "backfill" // Look their sectors up in the constituents CSV first
+)
+
+// validUnknownSector reports whether s names a known unknown-sector strategy
+func validUnknownSector(s string) bool {
+	switch s {
+	case "", unknownKeep, unknownDrop, unknownBackfill:
+		return true
+	}
+	return false
+}
+
+// backfillSectors fills unknown sectors from the constituents CSV at path,
+// returning the updated universe and how many sectors were filled
+func backfillSectors(u Universe, path string) (Universe, int, error) {
+	if path == "" {
+		return u, 0, fmt.Errorf("no constituents CSV configured for backfill")
+	}
+	ref, err := loadUniverseCSV(path)
+	if err != nil {
+		return u, 0, err
+	}
+	sectors := make(map[string]string, ref.Len())
+	for i, ticker := range ref.Tickers {
+		if ref.Sectors[i] != "" && ref.Sectors[i] != unknownSector {
+			sectors[ticker] = ref.Sectors[i]
+		}
+	}
+
+	u = u.clone()
+	filled := 0
+	for i, ticker := range u.Tickers {
+		if u.Sectors[i] != unknownSector {
+			continue
+		}
+		if sector, ok := sectors[strings.ToUpper(ticker)]; ok {
+			u.Sectors[i] = sector
+			filled++
+		}
+	}
+	return u, filled, nil
+}
+
+// readTickers parses newline-separated symbols, skipping blanks and # comments.
+// Symbols are upper-cased, validated, and de-duplicated in input order.
+func readTickers(r io.Reader) (Universe, error) {
+	var u Universe
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		ticker := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+		if ticker == "" || strings.HasPrefix(ticker, "#") {
+			continue
+		}
+		if !validTicker(ticker) {
+			log.Printf("Warning: skipping invalid ticker %q on line %d", ticker, line)
+			continue
+		}
+		if seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		u.add(ticker, stdinSector, "")
+	}
+	if err := scanner.Err(); err != nil {
+		return Universe{}, fmt.Errorf("error reading tickers: %v", err)
+	}
+
+	if u.Len() == 0 {
+		return Universe{}, fmt.Errorf("no tickers found in input")
+	}
+	return u, nil
+}
+
+// validTicker reports whether s looks like a Yahoo symbol
+func validTicker(s string) bool {
+	if s == "" || len(s) >= 10 {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '^', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// tickerCache holds the most recently scraped ticker universe
+type tickerCache struct {
+	mu       sync.Mutex
+	universe Universe
+	fetched  time.Time
+}
+
+// sp500Cache caches the scraped S&P 500 constituents between refreshes
+var sp500Cache tickerCache
+
+// get returns the cached universe if it is younger than ttl, otherwise it calls
+// fetch and caches the result. fresh forces a fetch; the cache is only replaced
+// when the fetch succeeds. The boolean reports whether the cache was used.
+func (c *tickerCache) get(ttl time.Duration, fetch TickerSource, fresh bool) (Universe, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !fresh && c.universe.Len() > 0 && time.Since(c.fetched) < ttl {
+		return c.universe.clone(), true, nil
+	}
+
+	u, err := fetch()
+	if err != nil {
+		return Universe{}, false, err
+	}
+	if u.Len() == 0 {
+		return Universe{}, false, fmt.Errorf("no tickers returned")
+	}
+	c.universe, c.fetched = u, time.Now()
+	return u.clone(), false, nil
+}
+
+// Supported index universes
+const (
+	indexSP500       = "sp500"
+	indexSP400       = "sp400"
+	indexSP600       = "sp600"
+	indexRussell1000 = "russell1000"
+)
+
+// indexUsage lists the accepted index values for error messages
+const indexUsage = "sp500, sp400, sp600 or russell1000, or a comma-separated combination"
+
+// wikipediaPages are the constituent pages of the indices scraped from Wikipedia
+var wikipediaPages = map[string]string{
+	indexSP500: "https://en.wikipedia.org/wiki/List_of_S%26P_500_companies",
+	indexSP400: "https://en.wikipedia.org/wiki/List_of_S%26P_400_companies",
+	indexSP600: "https://en.wikipedia.org/wiki/List_of_S%26P_600_companies",
+}
+
+// validIndex reports whether index names a supported universe or a
+// comma-separated combination of them ("" means the S&P 500)
+func validIndex(index string) bool {
+	for _, part := range splitList(index) {
+		if _, ok := wikipediaPages[part]; !ok && part != indexRussell1000 {
+			return false
+		}
+	}
+	return true
+}
+
+// getIndexTickers returns the constituents of the named index. The S&P indices
+// are scraped from Wikipedia; the Russell 1000 is loaded from the configured CSV
+// because its Wikipedia list is unreliable to scrape. A comma-separated list
+// returns the combined universe (see getCombinedTickers).
+func getIndexTickers(ctx context.Context, index string) (Universe, error) {
+	if indices := splitList(index); len(indices) > 1 {
+		return getCombinedTickers(ctx, indices)
+	}
+	switch index {
+	case "", indexSP500:
+		return getSP500Tickers(ctx)
+	case indexSP400:
+		return scrapeWikipediaIndex(ctx, "S&P 400", wikipediaPages[indexSP400])
+	case indexSP600:
+		return scrapeWikipediaIndex(ctx, "S&P 600", wikipediaPages[indexSP600])
+	case indexRussell1000:
+		if cfg.Russell1000CSV == "" {
+			return Universe{}, fmt.Errorf("no Russell 1000 CSV configured (set OMAHA_RUSSELL1000_CSV)")
+		}
+		return loadUniverseCSV(cfg.Russell1000CSV)
+	}
+	return Universe{}, fmt.Errorf("unknown index %q", index)
+}
+
+// getCombinedTickers fetches several indices concurrently, at most
+// cfg.ScrapeWorkers at a time, and merges them in the given order. A ticker in
+// several indices keeps the first index's sector and name and lists every
+// index in Sources. Any failed index fails the whole universe.
+func getCombinedTickers(ctx context.Context, indices []string) (Universe, error) {
+	var unique []string
+	for _, index := range indices {
+		if !slices.Contains(unique, index) {
+			unique = append(unique, index)
+		}
+	}
+	indices = unique
+
+	universes, errs := ProcessInParallel(ctx, indices, func(index string) (Universe, error) {
+		u, err := getIndexTickers(ctx, index)
+		if err != nil {
+			return Universe{}, fmt.Errorf("%s: %v", index, err)
+		}
+		return u, nil
+	}, cfg.ScrapeWorkers)
+	if len(errs) > 0 {
+		return Universe{}, errors.Join(errs...)
+	}
+
+	combined := Universe{Sources: make(map[string][]string)}
+	for i, u := range universes {
+		for j, ticker := range u.Tickers {
+			if _, ok := combined.Sources[ticker]; !ok {
+				combined.add(ticker, u.Sectors[j], u.Names[j])
+			}
+			combined.Sources[ticker] = append(combined.Sources[ticker], indices[i])
+		}
+	}
+	log.Printf("🔗 Combined %d indices into %d unique tickers\n", len(indices), combined.Len())
+	return combined, nil
+}
+
+// loadUniverseCSV reads constituents from a CSV file with a header row naming
+// symbol, sector and name columns (case-insensitive, in any order)
+func loadUniverseCSV(path string) (Universe, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Universe{}, fmt.Errorf("failed to open constituents CSV: %v", err)
+	}
+	defer f.Close()
+
+	u, err := readUniverseCSV(f)
+	if err != nil {
+		return Universe{}, fmt.Errorf("%s: %v", path, err)
+	}
+	log.Printf("📄 Loaded %d constituents from %s\n", u.Len(), path)
+	return u, nil
+}
+
+// readUniverseCSV parses a constituents CSV; see loadUniverseCSV for the format
+func readUniverseCSV(r io.Reader) (Universe, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return Universe{}, fmt.Errorf("failed to read header: %v", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, want := range []string{"symbol", "sector", "name"} {
+		if _, ok := cols[want]; !ok {
+			return Universe{}, fmt.Errorf("missing %q column in header", want)
+		}
+	}
+
+	field := func(record []string, col string) string {
+		if i := cols[col]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var u Universe
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Universe{}, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		ticker := strings.ToUpper(field(record, "symbol"))
+		if !validTicker(ticker) {
+			log.Printf("Warning: skipping invalid symbol %q on line %d", ticker, line)
+			continue
+		}
+		if seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+
+		sector := field(record, "sector")
+		if sector == "" {
+			sector = unknownSector
+		}
+		u.add(ticker, sector, field(record, "name"))
+	}
+
+	if u.Len() == 0 {
+		return Universe{}, fmt.Errorf("no valid constituents found")
+	}
+	return u, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// constituentsPage wraps rows in a constituents table like Wikipedia's
+func constituentsPage(rows string) string {
+	return `<html><body><table class="wikitable">
+<tbody>
+<tr><th>Symbol</th><th>Security</th><th>GICS Sector</th></tr>
+` + rows + `
+</tbody></table></body></html>`
+}
+
+func TestScrapeWikipediaIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        string
+		wantTickers []string
+		wantSectors []string
+		wantErr     bool
+	}{
+		{
+			name: "linked and plain symbols",
+			page: constituentsPage(`<tr><td><a href="#">AAA</a></td><td>Alpha</td><td>Tech</td></tr>
+<tr><td>BBB</td><td>Beta</td><td>Energy</td></tr>`),
+			wantTickers: []string{"AAA", "BBB"},
+			wantSectors: []string{"Tech", "Energy"},
+		},
+		{
+			name: "row without a sector cell",
+			page: constituentsPage(`<tr><td>AAA</td><td>Alpha</td><td>Tech</td></tr>
+<tr><td>BBB</td><td>Beta</td></tr>
+<tr><td>CCC</td><td>Gamma</td><td>Energy</td></tr>`),
+			wantTickers: []string{"AAA", "BBB", "CCC"},
+			wantSectors: []string{"Tech", unknownSector, "Energy"},
+		},
+		{
+			name: "duplicate symbol kept once",
+			page: constituentsPage(`<tr><td>AAA</td><td>Alpha</td><td>Tech</td></tr>
+<tr><td>AAA</td><td>Alpha</td><td>Energy</td></tr>`),
+			wantTickers: []string{"AAA"},
+			wantSectors: []string{"Tech"},
+		},
+		{
+			name:    "no constituents table",
+			page:    `<html><body><table class="wikitable"><tr><th>Date</th></tr></table></body></html>`,
+			wantErr: true,
+		},
+		{
+			name:    "no tickers",
+			page:    constituentsPage(``),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "page.html")
+			if err := os.WriteFile(path, []byte(tt.page), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			u, err := scrapeWikipediaIndex(context.Background(), "test", "file://"+path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := u.aligned(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(u.Tickers, tt.wantTickers) || !slices.Equal(u.Sectors, tt.wantSectors) {
+				t.Errorf("got %v / %v, want %v / %v", u.Tickers, u.Sectors, tt.wantTickers, tt.wantSectors)
+			}
+		})
+	}
+}
+
+func TestUniverseAligned(t *testing.T) {
+	tests := []struct {
+		name    string
+		u       Universe
+		wantErr bool
+	}{
+		{name: "empty", u: Universe{}},
+		{name: "aligned", u: Universe{Tickers: []string{"A"}, Sectors: []string{"Tech"}, Names: []string{""}}},
+		{name: "missing sector", u: Universe{Tickers: []string{"A", "B"}, Sectors: []string{"Tech"}, Names: []string{"", ""}}, wantErr: true},
+		{name: "missing name", u: Universe{Tickers: []string{"A"}, Sectors: []string{"Tech"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.u.aligned(); (err != nil) != tt.wantErr {
+				t.Errorf("aligned() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErrorLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int64
+		errors  int
+		reached int
+	}{
+		{name: "below the limit", max: 20, errors: 19, reached: 0},
+		{name: "at the limit", max: 20, errors: 20, reached: 1},
+		{name: "past the limit", max: 20, errors: 200, reached: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := errorLimit{max: tt.max}
+			var reached atomic.Int64
+			var wg sync.WaitGroup
+			for range tt.errors {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, ok := limit.record(); ok {
+						reached.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := reached.Load(); got != int64(tt.reached) {
+				t.Errorf("limit reached %d times, want %d", got, tt.reached)
+			}
+			if got := limit.count.Load(); got != int64(tt.errors) {
+				t.Errorf("counted %d errors, want %d", got, tt.errors)
+			}
+		})
+	}
+}
+
+func TestScrapeFailedPage(t *testing.T) {
+	_, err := scrapeWikipediaIndex(context.Background(), "test", "file://"+filepath.Join(t.TempDir(), "missing.html"))
+	if err == nil {
+		t.Fatal("scraping a missing page succeeded")
+	}
+}
+
+func TestScrapeCancellation(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer hung.Close()
+	defer close(release)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, constituentsPage(`<tr><td>AAA</td><td>Alpha</td><td>Tech</td></tr>`))
+	}))
+	defer ok.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{name: "responsive page", url: ok.URL, ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 5*time.Second)
+		}},
+		{name: "deadline", url: hung.URL, wantErr: context.DeadlineExceeded, ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}},
+		{name: "cancelled mid-visit", url: hung.URL, wantErr: context.Canceled, ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return ctx, cancel
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			begun := time.Now()
+			u, err := scrapeWikipediaIndex(ctx, "test", tt.url)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(begun); elapsed > 2*time.Second {
+				t.Errorf("scrape took %v", elapsed)
+			}
+			if tt.wantErr == nil && !slices.Equal(u.Tickers, []string{"AAA"}) {
+				t.Errorf("tickers = %v", u.Tickers)
+			}
+		})
+	}
+}
+
+func TestScrapeRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer hung.Close()
+	defer close(release)
+	withConfig(t, func(c *Config) { c.ScrapeTimeout = 50 * time.Millisecond })
+
+	begun := time.Now()
+	if _, err := scrapeWikipediaIndex(context.Background(), "test", hung.URL); err == nil {
+		t.Fatal("scrape of a hung page succeeded")
+	}
+	if elapsed := time.Since(begun); elapsed > 2*time.Second {
+		t.Errorf("scrape took %v past a %v timeout", elapsed, cfg.ScrapeTimeout)
+	}
+}
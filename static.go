@@ -0,0 +1,32 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// staticFiles holds the front-end assets so the binary is self-contained
+//
+//go:embed static
+var staticFiles embed.FS
+
+// staticHandler serves the embedded assets under /static/.
+// fs.FS rejects ".." and absolute paths, so requests can't escape the static
+// directory, and directory listings are disabled.
+func staticHandler() http.Handler {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		log.Fatalf("Failed to load static assets: %v", err)
+	}
+	files := http.StripPrefix("/static/", http.FileServerFS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
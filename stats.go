@@ -0,0 +1,47 @@
+package main
+
+import "math"
+
+// tradingDaysPerYear annualizes daily volatility
+const tradingDaysPerYear = 252
+
+// seriesStats accumulates a ticker's daily-return statistics one bar at a
+// time, so volatility and drawdown cost constant memory however long the
+// window is. The variance uses Welford's online algorithm, which stays
+// accurate where a running sum of squares would lose precision.
+type seriesStats struct {
+	n    int     // Daily returns seen
+	mean float64 // Running mean of the daily returns
+	m2   float64 // Running sum of squared deviations from the mean
+
+	peak        float64 // Highest price since the baseline
+	maxDrawdown float64 // Largest fall from a peak, as a fraction (<= 0)
+}
+
+// addReturn adds one daily return
+func (s *seriesStats) addReturn(r float64) {
+	s.n++
+	delta := r - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (r - s.mean)
+}
+
+// addPrice updates the running peak and drawdown with a price
+func (s *seriesStats) addPrice(p float64) {
+	if p > s.peak {
+		s.peak = p
+		return
+	}
+	if s.peak > 0 {
+		s.maxDrawdown = min(s.maxDrawdown, p/s.peak-1)
+	}
+}
+
+// volatility returns the annualized sample standard deviation of the daily
+// returns, reporting false with fewer than two returns
+func (s seriesStats) volatility() (float64, bool) {
+	if s.n < 2 {
+		return 0, false
+	}
+	return math.Sqrt(s.m2/float64(s.n-1)) * math.Sqrt(tradingDaysPerYear), true
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// batchVolatility is the two-pass annualized sample standard deviation
+func batchVolatility(returns []float64) float64 {
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+	var ss float64
+	for _, r := range returns {
+		ss += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(ss/float64(len(returns)-1)) * math.Sqrt(tradingDaysPerYear)
+}
+
+// batchMaxDrawdown is the largest fall from a running peak over the whole series
+func batchMaxDrawdown(prices []float64) float64 {
+	worst := 0.0
+	for i, p := range prices {
+		for _, peak := range prices[:i] {
+			worst = min(worst, p/peak-1)
+		}
+	}
+	return worst
+}
+
+// dailyReturns returns the simple returns between consecutive prices
+func dailyReturns(prices []float64) []float64 {
+	var out []float64
+	for i := 1; i < len(prices); i++ {
+		out = append(out, prices[i]/prices[i-1]-1)
+	}
+	return out
+}
+
+func TestSeriesStatsMatchesBatch(t *testing.T) {
+	long := make([]float64, 2000)
+	for i := range long {
+		long[i] = 1e6 + 50*math.Sin(float64(i)/7) // Large level, small moves
+	}
+	tests := []struct {
+		name   string
+		prices []float64
+	}{
+		{name: "rising", prices: []float64{100, 101, 102, 103, 104}},
+		{name: "falling", prices: []float64{100, 95, 90, 85}},
+		{name: "v-shape", prices: []float64{100, 80, 60, 90, 120, 70}},
+		{name: "flat", prices: []float64{50, 50, 50}},
+		{name: "long window", prices: long},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s seriesStats
+			s.addPrice(tt.prices[0])
+			for i := 1; i < len(tt.prices); i++ {
+				s.addReturn(tt.prices[i]/tt.prices[i-1] - 1)
+				s.addPrice(tt.prices[i])
+			}
+			vol, ok := s.volatility()
+			if !ok {
+				t.Fatal("no volatility")
+			}
+			if want := batchVolatility(dailyReturns(tt.prices)); math.Abs(vol-want) > 1e-9*max(1, want) {
+				t.Errorf("volatility = %v, batch %v", vol, want)
+			}
+			if want := batchMaxDrawdown(tt.prices); math.Abs(s.maxDrawdown-want) > 1e-12 {
+				t.Errorf("max drawdown = %v, batch %v", s.maxDrawdown, want)
+			}
+		})
+	}
+}
+
+func TestSeriesStatsTooFewReturns(t *testing.T) {
+	for _, n := range []int{0, 1} {
+		var s seriesStats
+		for range n {
+			s.addReturn(0.01)
+		}
+		if _, ok := s.volatility(); ok {
+			t.Errorf("volatility reported with %d returns", n)
+		}
+	}
+}
+
+func TestFetchStatsMatchBatch(t *testing.T) {
+	prices := []float64{100, 104, 98, 97, 103, 110, 92, 95, 101, 99, 105, 108, 100, 96, 102, 107, 111, 106, 109, 112, 115}
+	tests := []struct {
+		name    string
+		metrics MetricSet
+	}{
+		{name: "all metrics", metrics: allMetrics},
+		{name: "none", metrics: MetricSet{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return prices[i] })
+			fopts := defaultFetchOptions()
+			fopts.Metrics = tt.metrics
+			res, err := getMTDReturn(context.Background(), "AAA", septemberStart, septemberEnd, fopts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.metrics.Volatility {
+				if res.Volatility != nil || res.MaxDrawdown != nil {
+					t.Errorf("series metrics computed when off: %v %v", res.Volatility, res.MaxDrawdown)
+				}
+				return
+			}
+			if res.Volatility == nil || math.Abs(*res.Volatility-batchVolatility(dailyReturns(prices))) > 1e-9 {
+				t.Errorf("volatility = %v, batch %v", res.Volatility, batchVolatility(dailyReturns(prices)))
+			}
+			if res.MaxDrawdown == nil || math.Abs(*res.MaxDrawdown-batchMaxDrawdown(prices)) > 1e-12 {
+				t.Errorf("max drawdown = %v, batch %v", res.MaxDrawdown, batchMaxDrawdown(prices))
+			}
+		})
+	}
+}
+
+func TestVolatilityLookback(t *testing.T) {
+	// Choppy in August, a steady climb in September
+	price := func(i int) float64 {
+		if i < 11 { // Aug 15-29
+			return 100 + 20*float64(i%2)
+		}
+		return 100 + float64(i)
+	}
+	bars := dailyBars("2025-08-15", "2025-09-30", price)
+	closesFrom := func(date string) []float64 {
+		var out []float64
+		for _, b := range bars {
+			if b.date >= date {
+				out = append(out, b.close)
+			}
+		}
+		return out
+	}
+	tests := []struct {
+		name       string
+		lookback   string // VolatilityStart; empty uses the window
+		volCloses  []float64
+		wantReturn float64
+	}{
+		{name: "window only", volCloses: closesFrom("2025-09-02"), wantReturn: price(31)/price(11) - 1},
+		{name: "trailing lookback", lookback: "2025-08-18", volCloses: closesFrom("2025-08-18"), wantReturn: price(31)/price(11) - 1},
+		{name: "lookback from the last August session", lookback: "2025-08-29", volCloses: closesFrom("2025-08-29"), wantReturn: price(31)/price(11) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = bars
+			fopts := defaultFetchOptions()
+			if tt.lookback != "" {
+				fopts.VolatilityStart = date(t, tt.lookback)
+			}
+			res, err := getMTDReturn(context.Background(), "AAA", septemberStart, septemberEnd, fopts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(res.Return-tt.wantReturn) > 1e-12 || res.BarCount != 21 {
+				t.Errorf("return = %v over %d bars, want %v over the 21 September sessions", res.Return, res.BarCount, tt.wantReturn)
+			}
+			if want := batchVolatility(dailyReturns(tt.volCloses)); res.Volatility == nil || math.Abs(*res.Volatility-want) > 1e-9 {
+				t.Errorf("volatility = %v, want %v", res.Volatility, want)
+			}
+			// Drawdown stays on the window: September only climbs
+			if res.MaxDrawdown == nil || *res.MaxDrawdown != 0 {
+				t.Errorf("max drawdown = %v, want 0", res.MaxDrawdown)
+			}
+		})
+	}
+}
+
+func TestRunVolatilityLookback(t *testing.T) {
+	tests := []struct {
+		lookback  int
+		wantStart string // Empty when the window already covers the lookback
+	}{
+		{lookback: 0},
+		{lookback: 10},
+		{lookback: 30, wantStart: "2025-08-18"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.lookback), func(t *testing.T) {
+			f := newFakeRun(t)
+			withConfig(t, func(c *Config) { c.VolatilityLookbackDays = tt.lookback })
+			f.charts["AAA"] = laborDayBars
+			_, summary, err := runFake(t, []string{"AAA"}, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if summary.VolatilityStart != nil {
+				got = summary.VolatilityStart.Format("2006-01-02")
+			}
+			if got != tt.wantStart {
+				t.Errorf("volatility start = %q, want %q", got, tt.wantStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// streamBuffer is how many ticker events may queue for a slow stream client
+// before further events are dropped, so a client can never stall a refresh
+const streamBuffer = 256
+
+// handleStream streams refresh progress as Server-Sent Events: a "result"
+// event for each ticker as it completes and a "summary" event when the run's
+// results are swapped in. The stream stays open until the client disconnects.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The server's write timeout would cut off a long-lived stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	results, unsubscribeResults := s.events.Subscribe(topicTickerResult, streamBuffer)
+	defer unsubscribeResults()
+	updates, unsubscribeUpdates := s.events.Subscribe(topicResultsUpdated, 1)
+	defer unsubscribeUpdates()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-results:
+			err = writeSSE(w, "result", e.Results[0])
+		case e := <-updates:
+			err = writeSSE(w, "summary", e.Summary)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			log.Printf("Warning: closing event stream: %v", err)
+			return
+		}
+	}
+}
+
+// writeSSE writes v as a single Server-Sent Event with the given event name
+func writeSSE(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
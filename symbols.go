@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultSymbolSuffixes maps share-class suffixes in scraped symbols (e.g.
+// "BRK.B") to Yahoo's dash form ("BRK-B")
+var defaultSymbolSuffixes = map[string]string{
+	".A": "-A",
+	".B": "-B",
+	"/A": "-A",
+	"/B": "-B",
+}
+
+// defaultShareClasses maps the extra share classes of well-known multi-class
+// companies to the class kept when consolidating
+var defaultShareClasses = map[string]string{
+	"GOOG":  "GOOGL",
+	"FOX":   "FOXA",
+	"NWS":   "NWSA",
+	"BRK-A": "BRK-B",
+}
+
+// envSymbolMap reads comma-separated from=to symbol rewrites (e.g. ".B=-B,.WI="),
+// upper-cased, falling back to def when unset or invalid. "default" also selects def.
+func envSymbolMap(key string, def map[string]string) map[string]string {
+	v := os.Getenv(key)
+	if v == "" || v == "default" {
+		return def
+	}
+	m := make(map[string]string)
+	for _, pair := range splitList(v) {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" {
+			log.Printf("Warning: invalid %s=%q, using the default", key, v)
+			return def
+		}
+		m[strings.ToUpper(from)] = strings.ToUpper(to)
+	}
+	return m
+}
+
+// envShareClasses reads the share-class consolidation map: off when unset,
+// defaultShareClasses for "default", or explicit from=to pairs
+func envShareClasses(key string) map[string]string {
+	if os.Getenv(key) == "" {
+		return nil
+	}
+	return envSymbolMap(key, defaultShareClasses)
+}
+
+// consolidateShareClasses drops each extra share class whose kept class is
+// also in the universe, so a company counts once in sector and index stats.
+// A class whose counterpart is absent stays.
+func consolidateShareClasses(u Universe, classes map[string]string) (Universe, map[string]string) {
+	if len(classes) == 0 {
+		return u, nil
+	}
+	present := make(map[string]bool, u.Len())
+	for _, ticker := range u.Tickers {
+		present[ticker] = true
+	}
+
+	var out Universe
+	out.Sources = u.Sources
+	var merged map[string]string
+	for i, ticker := range u.Tickers {
+		if keep, ok := classes[ticker]; ok && keep != ticker && present[keep] {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[ticker] = keep
+			continue
+		}
+		out.add(ticker, u.Sectors[i], u.Names[i])
+	}
+	return out, merged
+}
+
+// normalizeSymbol rewrites the longest matching suffix of s per suffixes,
+// returning an error when the result still isn't a valid Yahoo symbol
+func normalizeSymbol(s string, suffixes map[string]string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	best := ""
+	for from := range suffixes {
+		if len(from) > len(best) && len(from) < len(s) && strings.HasSuffix(s, from) {
+			best = from
+		}
+	}
+	if best != "" {
+		s = strings.TrimSuffix(s, best) + suffixes[best]
+	}
+	if !validTicker(s) {
+		return "", fmt.Errorf("unsupported symbol %q", s)
+	}
+	return s, nil
+}
+
+// SkippedTicker is a constituent left out of a run before fetching
+type SkippedTicker struct {
+	Ticker string `json:"ticker"`
+	Reason string `json:"reason"`
+}
+
+// normalizeUniverse rewrites each symbol into Yahoo's form, dropping the ones
+// that can't be normalized or that collide with an earlier symbol
+func normalizeUniverse(u Universe, suffixes map[string]string) (Universe, []SkippedTicker) {
+	var out Universe
+	var skipped []SkippedTicker
+	seen := make(map[string]string, u.Len())
+	for i, raw := range u.Tickers {
+		ticker, err := normalizeSymbol(raw, suffixes)
+		if err != nil {
+			skipped = append(skipped, SkippedTicker{Ticker: raw, Reason: err.Error()})
+			continue
+		}
+		if first, ok := seen[ticker]; ok {
+			skipped = append(skipped, SkippedTicker{Ticker: raw, Reason: fmt.Sprintf("duplicate of %s as %s", first, ticker)})
+			continue
+		}
+		seen[ticker] = raw
+		out.add(ticker, u.Sectors[i], u.Names[i])
+		if src, ok := u.Sources[raw]; ok {
+			if out.Sources == nil {
+				out.Sources = make(map[string][]string)
+			}
+			out.Sources[ticker] = src
+		}
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Ticker < skipped[j].Ticker })
+	return out, skipped
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	custom := map[string]string{".WI": "", ".B": "-B", ".PR.A": "-PA"}
+	tests := []struct {
+		symbol   string
+		suffixes map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{symbol: "BRK.B", suffixes: defaultSymbolSuffixes, want: "BRK-B"},
+		{symbol: "BF/B", suffixes: defaultSymbolSuffixes, want: "BF-B"},
+		{symbol: " brk.b ", suffixes: defaultSymbolSuffixes, want: "BRK-B"},
+		{symbol: "AAPL", suffixes: defaultSymbolSuffixes, want: "AAPL"},
+		{symbol: "HEI/A", suffixes: defaultSymbolSuffixes, want: "HEI-A"},
+		{symbol: "ABC/C", suffixes: defaultSymbolSuffixes, wantErr: true},
+		{symbol: "XYZ.WI", suffixes: custom, want: "XYZ"},
+		{symbol: "PSA.PR.A", suffixes: custom, want: "PSA-PA"},
+		{symbol: ".B", suffixes: custom, want: ".B"},
+		{symbol: "ABC$", suffixes: nil, wantErr: true},
+		{symbol: "", suffixes: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			got, err := normalizeSymbol(tt.symbol, tt.suffixes)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("normalizeSymbol(%q) = %q, %v; want %q, error %t", tt.symbol, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeUniverse(t *testing.T) {
+	var u Universe
+	for _, s := range []string{"AAPL", "BRK.B", "BRK-B", "ABC/C", "BF/B"} {
+		u.add(s, "Sector "+s, "")
+	}
+	got, skipped := normalizeUniverse(u, defaultSymbolSuffixes)
+	if want := []string{"AAPL", "BRK-B", "BF-B"}; !slices.Equal(got.Tickers, want) {
+		t.Errorf("tickers = %v, want %v", got.Tickers, want)
+	}
+	if want := []string{"Sector AAPL", "Sector BRK.B", "Sector BF/B"}; !slices.Equal(got.Sectors, want) {
+		t.Errorf("sectors = %v, want %v", got.Sectors, want)
+	}
+	wantSkipped := []SkippedTicker{
+		{Ticker: "ABC/C", Reason: `unsupported symbol "ABC/C"`},
+		{Ticker: "BRK-B", Reason: "duplicate of BRK.B as BRK-B"},
+	}
+	if !slices.Equal(skipped, wantSkipped) {
+		t.Errorf("skipped = %+v, want %+v", skipped, wantSkipped)
+	}
+}
+
+func TestExcludeTickersNormalized(t *testing.T) {
+	var u Universe
+	for _, s := range []string{"AAPL", "BRK-B", "BF-B", "XOM"} {
+		u.add(s, "Tech", "")
+	}
+	u.Sectors[3] = "Energy"
+	tests := []struct {
+		exclude []string
+		want    []string
+	}{
+		{exclude: nil, want: []string{"AAPL", "BRK-B", "BF-B", "XOM"}},
+		{exclude: []string{"BRK.B"}, want: []string{"AAPL", "BF-B", "XOM"}},
+		{exclude: []string{"bf/b", "brk-b"}, want: []string{"AAPL", "XOM"}},
+		{exclude: []string{"energy"}, want: []string{"AAPL", "BRK-B", "BF-B"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.exclude), func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.SymbolSuffixes = defaultSymbolSuffixes })
+			got, n := excludeTickers(u, tt.exclude)
+			if !slices.Equal(got.Tickers, tt.want) || n != u.Len()-len(tt.want) {
+				t.Errorf("kept %v (%d removed), want %v", got.Tickers, n, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSymbolMap(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{value: "", want: defaultSymbolSuffixes},
+		{value: "default", want: defaultSymbolSuffixes},
+		{value: ".wi=,.b=-b", want: map[string]string{".WI": "", ".B": "-B"}},
+		{value: ".B", want: defaultSymbolSuffixes},
+		{value: "=-B", want: defaultSymbolSuffixes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("OMAHA_TEST_SUFFIXES", tt.value)
+			if got := envSymbolMap("OMAHA_TEST_SUFFIXES", defaultSymbolSuffixes); !maps.Equal(got, tt.want) {
+				t.Errorf("envSymbolMap(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsolidateShareClasses(t *testing.T) {
+	universe := func(tickers ...string) Universe {
+		var u Universe
+		for _, ticker := range tickers {
+			u.add(ticker, "Communication Services", ticker+" Inc")
+		}
+		return u
+	}
+	tests := []struct {
+		name       string
+		tickers    []string
+		classes    map[string]string
+		want       []string
+		wantMerged map[string]string
+	}{
+		{name: "off", tickers: []string{"GOOG", "GOOGL", "FOX", "FOXA"}, want: []string{"GOOG", "GOOGL", "FOX", "FOXA"}},
+		{
+			name:       "alphabet",
+			tickers:    []string{"AAPL", "GOOG", "GOOGL"},
+			classes:    defaultShareClasses,
+			want:       []string{"AAPL", "GOOGL"},
+			wantMerged: map[string]string{"GOOG": "GOOGL"},
+		},
+		{
+			name:       "fox listed before its kept class",
+			tickers:    []string{"FOX", "FOXA", "GOOG", "GOOGL"},
+			classes:    defaultShareClasses,
+			want:       []string{"FOXA", "GOOGL"},
+			wantMerged: map[string]string{"GOOG": "GOOGL", "FOX": "FOXA"},
+		},
+		{name: "kept class absent", tickers: []string{"GOOG", "FOX"}, classes: defaultShareClasses, want: []string{"GOOG", "FOX"}},
+		{
+			name:       "custom map",
+			tickers:    []string{"GOOG", "GOOGL", "FOX", "FOXA"},
+			classes:    map[string]string{"GOOGL": "GOOG"},
+			want:       []string{"GOOG", "FOX", "FOXA"},
+			wantMerged: map[string]string{"GOOGL": "GOOG"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, merged := consolidateShareClasses(universe(tt.tickers...), tt.classes)
+			if err := got.aligned(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got.Tickers, tt.want) || !maps.Equal(merged, tt.wantMerged) {
+				t.Errorf("got %v merged %v, want %v merged %v", got.Tickers, merged, tt.want, tt.wantMerged)
+			}
+		})
+	}
+}
+
+func TestEnvShareClasses(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{value: "", want: nil},
+		{value: "default", want: defaultShareClasses},
+		{value: "goog=googl", want: map[string]string{"GOOG": "GOOGL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("OMAHA_TEST_SHARE_CLASSES", tt.value)
+			if got := envShareClasses("OMAHA_TEST_SHARE_CLASSES"); !maps.Equal(got, tt.want) {
+				t.Errorf("envShareClasses(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunConsolidatesShareClasses(t *testing.T) {
+	tests := []struct {
+		name        string
+		classes     map[string]string
+		wantResults int
+	}{
+		{name: "off", wantResults: 4},
+		{name: "default", classes: defaultShareClasses, wantResults: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			withConfig(t, func(c *Config) { c.ShareClasses = tt.classes })
+			for _, ticker := range []string{"GOOG", "GOOGL", "FOX", "FOXA"} {
+				f.charts[ticker] = dailyBars("2025-09-01", "2025-09-30", func(i int) float64 { return 100 + float64(i) })
+			}
+			results, summary, err := runFake(t, []string{"GOOG", "GOOGL", "FOX", "FOXA"}, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tt.wantResults || len(summary.Consolidated) != 4-tt.wantResults {
+				t.Errorf("%d results, consolidated %v; want %d results", len(results), summary.Consolidated, tt.wantResults)
+			}
+			if tt.classes != nil && (f.chartCalls("GOOG") != 0 || f.chartCalls("FOX") != 0) {
+				t.Error("consolidated classes were still fetched")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// traceKey and spanKey are context keys for request tracing
+type (
+	traceKey struct{}
+	spanKey  struct{}
+)
+
+// withTrace returns a context carrying a trace ID (one per run)
+func withTrace(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceID)
+}
+
+// withSpan returns a context carrying a span ID (one per ticker fetch)
+func withSpan(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanKey{}, spanID)
+}
+
+// traceLabel formats the trace and span IDs in ctx for log lines, e.g. "run-id/AAPL"
+func traceLabel(ctx context.Context) string {
+	trace, _ := ctx.Value(traceKey{}).(string)
+	span, _ := ctx.Value(spanKey{}).(string)
+	if span == "" {
+		return trace
+	}
+	return trace + "/" + span
+}
+
+// percentile returns the p-th percentile (0-100) of the durations using nearest rank
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
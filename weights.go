@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Weighting modes for sector and index averages
+const (
+	weightingEqual  = "equal"  // Default: every ticker counts the same
+	weightingCustom = "custom" // Tickers weighted by the OMAHA_WEIGHTS_CSV portfolio
+)
+
+// validWeighting reports whether s names a known weighting mode
+func validWeighting(s string) bool {
+	switch s {
+	case "", weightingEqual, weightingCustom:
+		return true
+	}
+	return false
+}
+
+// loadWeightsCSV reads a portfolio's weights from a CSV with a header row
+// containing "symbol" (or "ticker") and "weight" columns. Symbols are
+// normalized like scraped ones, so BRK.B matches BRK-B. Weights needn't sum
+// to 1; averages normalize them per group.
+func loadWeightsCSV(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open weights CSV: %v", err)
+	}
+	defer f.Close()
+
+	weights, err := readWeightsCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	log.Printf("⚖️  Loaded %d weights from %s\n", len(weights), path)
+	return weights, nil
+}
+
+// readWeightsCSV parses a weights CSV; see loadWeightsCSV for the format
+func readWeightsCSV(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	symbolCol, weightCol := -1, -1
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "symbol", "ticker":
+			symbolCol = i
+		case "weight":
+			weightCol = i
+		}
+	}
+	if symbolCol < 0 {
+		return nil, fmt.Errorf(`missing "symbol" column in header`)
+	}
+	if weightCol < 0 {
+		return nil, fmt.Errorf(`missing "weight" column in header`)
+	}
+
+	weights := make(map[string]float64)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if symbolCol >= len(record) || weightCol >= len(record) {
+			log.Printf("Warning: skipping short weights row on line %d", line)
+			continue
+		}
+
+		ticker, err := normalizeSymbol(record[symbolCol], cfg.SymbolSuffixes)
+		if err != nil {
+			log.Printf("Warning: skipping %v on line %d", err, line)
+			continue
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(record[weightCol]), 64)
+		if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			log.Printf("Warning: skipping invalid weight %q for %s on line %d", record[weightCol], ticker, line)
+			continue
+		}
+		weights[ticker] += w // Repeated rows add up, as for several lots of one holding
+	}
+
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no valid weights found")
+	}
+	return weights, nil
+}
+
+// missingWeights returns the universe's tickers absent from weights, which
+// count with zero weight
+func missingWeights(u Universe, weights map[string]float64) []string {
+	var missing []string
+	for _, ticker := range u.Tickers {
+		if _, ok := weights[ticker]; !ok {
+			missing = append(missing, ticker)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadWeightsCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{name: "symbol and weight", csv: "symbol,weight\nAAPL,0.6\nMSFT,0.4\n", want: map[string]float64{"AAPL": 0.6, "MSFT": 0.4}},
+		{name: "ticker column, extra columns", csv: "name,Ticker,Weight\nApple,aapl,3\n", want: map[string]float64{"AAPL": 3}},
+		{name: "normalized symbols", csv: "symbol,weight\nBRK.B,1\n", want: map[string]float64{"BRK-B": 1}},
+		{name: "repeated rows add up", csv: "symbol,weight\nAAPL,1\nAAPL,2\n", want: map[string]float64{"AAPL": 3}},
+		{
+			name: "invalid rows skipped",
+			csv:  "symbol,weight\nAAPL,1\nMSFT,-1\nNVDA,abc\nAMZN,NaN\nGOOG\n",
+			want: map[string]float64{"AAPL": 1},
+		},
+		{name: "no symbol column", csv: "name,weight\nApple,1\n", wantErr: true},
+		{name: "no weight column", csv: "symbol,shares\nAAPL,1\n", wantErr: true},
+		{name: "no valid weights", csv: "symbol,weight\nAAPL,-1\n", wantErr: true},
+		{name: "empty", csv: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readWeightsCSV(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("weights = %v, want %v", got, tt.want)
+			}
+			for ticker, w := range tt.want {
+				if math.Abs(got[ticker]-w) > 1e-12 {
+					t.Errorf("weight of %s = %v, want %v", ticker, got[ticker], w)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedSectorReturns(t *testing.T) {
+	weight := func(w float64) *float64 { return &w }
+	tests := []struct {
+		name    string
+		results []Result
+		want    float64
+	}{
+		{
+			name:    "equal weighting",
+			results: []Result{{Ticker: "A", Sector: "Tech", Return: 0.1}, {Ticker: "B", Sector: "Tech", Return: 0.3}},
+			want:    0.2,
+		},
+		{
+			name: "weights normalized in the sector",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1, Weight: weight(3)},
+				{Ticker: "B", Sector: "Tech", Return: 0.3, Weight: weight(1)},
+			},
+			want: 0.15,
+		},
+		{
+			name: "zero weight ignored",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1, Weight: weight(2)},
+				{Ticker: "B", Sector: "Tech", Return: 0.9, Weight: weight(0)},
+			},
+			want: 0.1,
+		},
+		{
+			name: "all zero weights fall back to equal",
+			results: []Result{
+				{Ticker: "A", Sector: "Tech", Return: 0.1, Weight: weight(0)},
+				{Ticker: "B", Sector: "Tech", Return: 0.3, Weight: weight(0)},
+			},
+			want: 0.2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sectors := calculateSectorReturns(tt.results)
+			if len(sectors) != 1 || math.Abs(sectors[0].AvgReturn-tt.want) > 1e-12 {
+				t.Errorf("sectors = %+v, want Tech at %v", sectors, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCustomWeighting(t *testing.T) {
+	// AAA gains 10%, BBB 30% and CCC 50% over September
+	gain := func(pct float64) func(int) float64 {
+		return func(i int) float64 {
+			if i == 0 {
+				return 100
+			}
+			return 100 + pct
+		}
+	}
+	tests := []struct {
+		name           string
+		weighting      string
+		weights        string
+		wantIndex      float64
+		wantWeighting  string
+		wantUnweighted []string
+	}{
+		{name: "equal", weighting: weightingEqual, wantIndex: 0.3, wantWeighting: weightingEqual},
+		{name: "custom", weighting: weightingCustom, weights: "symbol,weight\nAAA,3\nBBB,1\nCCC,0\n", wantIndex: 0.15, wantWeighting: weightingCustom},
+		{
+			name:           "missing ticker weighs nothing",
+			weighting:      weightingCustom,
+			weights:        "symbol,weight\nAAA,1\nBBB,1\n",
+			wantIndex:      0.2,
+			wantWeighting:  weightingCustom,
+			wantUnweighted: []string{"CCC"},
+		},
+		{name: "custom without a file", weighting: weightingCustom, wantIndex: 0.3, wantWeighting: weightingEqual},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRun(t)
+			f.charts["AAA"] = dailyBars("2025-09-01", "2025-09-30", gain(10))
+			f.charts["BBB"] = dailyBars("2025-09-01", "2025-09-30", gain(30))
+			f.charts["CCC"] = dailyBars("2025-09-01", "2025-09-30", gain(50))
+			path := ""
+			if tt.weights != "" {
+				path = filepath.Join(t.TempDir(), "weights.csv")
+				if err := os.WriteFile(path, []byte(tt.weights), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			withConfig(t, func(c *Config) { c.WeightsCSV = path })
+
+			results, summary, err := runFake(t, []string{"AAA", "BBB", "CCC"}, RunOptions{Weighting: tt.weighting})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(summary.IndexReturn-tt.wantIndex) > 1e-12 {
+				t.Errorf("index return = %v, want %v", summary.IndexReturn, tt.wantIndex)
+			}
+			if summary.Weighting != tt.wantWeighting || !slices.Equal(summary.Unweighted, tt.wantUnweighted) {
+				t.Errorf("weighting %s, unweighted %v; want %s, %v", summary.Weighting, summary.Unweighted, tt.wantWeighting, tt.wantUnweighted)
+			}
+			if sectors := calculateSectorReturns(results); math.Abs(sectors[0].AvgReturn-tt.wantIndex) > 1e-12 {
+				t.Errorf("Tech average = %v, want %v", sectors[0].AvgReturn, tt.wantIndex)
+			}
+			if w := resultFor(t, results, "CCC").Weight; (w != nil) != (tt.wantWeighting == weightingCustom) {
+				t.Errorf("CCC weight = %v under %s weighting", w, tt.wantWeighting)
+			}
+		})
+	}
+}
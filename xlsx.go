@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Workbook sheet names
+const (
+	xlsxTickerSheet = "Tickers"
+	xlsxSectorSheet = "Sectors"
+)
+
+// xlsxStyles holds the style IDs registered on a workbook
+type xlsxStyles struct {
+	header    int
+	ret       int // Human-facing return cells, in percent or bps
+	rawReturn int // The unitless Return column, to the CSV's six decimals
+	volume    int
+	gain      int // Conditional formats by sign
+	loss      int
+}
+
+// writeResultsToXLSX writes the same ticker and sector tables as
+// writeResultsToCSV to an Excel workbook, one sheet each. Returns are
+// numeric cells with a percent (or bps) format, colored by sign.
+func writeResultsToXLSX(results []Result, sectorReturns []SectorReturn, filename string, opts CSVOptions) error {
+	units := normalizeUnits(opts.Units)
+	results = sortResultRows(results, opts.SortBy)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	styles, err := newXLSXStyles(f, units)
+	if err != nil {
+		return fmt.Errorf("failed to create workbook styles: %v", err)
+	}
+	if err := f.SetSheetName("Sheet1", xlsxTickerSheet); err != nil {
+		return fmt.Errorf("failed to create workbook: %v", err)
+	}
+	if _, err := f.NewSheet(xlsxSectorSheet); err != nil {
+		return fmt.Errorf("failed to create workbook: %v", err)
+	}
+	if err := writeTickerSheet(f, styles, results, units); err != nil {
+		return fmt.Errorf("failed to write tickers sheet: %v", err)
+	}
+	if err := writeSectorSheet(f, styles, sectorReturns, units, opts.Benchmark); err != nil {
+		return fmt.Errorf("failed to write sectors sheet: %v", err)
+	}
+
+	// Same temp-and-rename as the CSV, so readers never see a partial workbook
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create workbook: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := f.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write workbook: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write workbook: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write workbook: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace workbook: %v", err)
+	}
+	return nil
+}
+
+// newXLSXStyles registers the workbook's cell and conditional styles
+func newXLSXStyles(f *excelize.File, units string) (xlsxStyles, error) {
+	retFormat := "0.00%"
+	if units == unitsBps {
+		retFormat = "0"
+	}
+	rawFormat, volumeFormat := "0.000000", "#,##0"
+
+	var s xlsxStyles
+	var err error
+	if s.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return s, err
+	}
+	if s.ret, err = f.NewStyle(&excelize.Style{CustomNumFmt: &retFormat}); err != nil {
+		return s, err
+	}
+	if s.rawReturn, err = f.NewStyle(&excelize.Style{CustomNumFmt: &rawFormat}); err != nil {
+		return s, err
+	}
+	if s.volume, err = f.NewStyle(&excelize.Style{CustomNumFmt: &volumeFormat}); err != nil {
+		return s, err
+	}
+	if s.gain, err = f.NewConditionalStyle(&excelize.Style{Font: &excelize.Font{Color: "006100"}}); err != nil {
+		return s, err
+	}
+	if s.loss, err = f.NewConditionalStyle(&excelize.Style{Font: &excelize.Font{Color: "9C0006"}}); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// writeTickerSheet writes one row per result, in the CSV's column order
+func writeTickerSheet(f *excelize.File, s xlsxStyles, results []Result, units string) error {
+	cols := newResultColumns(results)
+	header := cols.header(units)
+	if err := writeXLSXHeader(f, xlsxTickerSheet, s, header); err != nil {
+		return err
+	}
+
+	// Return columns, by 1-based index, for number formats and coloring: the
+	// MTD column and every optional column after Baseline_Date but the flag
+	// and the currency code
+	retCols := []int{4}
+	rawCol, volumeCol := 3, 9
+	for i := 17; i <= len(header); i++ {
+		if h := header[i-1]; h != "Annualized_Capped" && h != "Currency" {
+			retCols = append(retCols, i)
+		}
+	}
+
+	for i, r := range results {
+		row := []any{
+			r.Ticker,
+			r.Sector,
+			xlsxNumber(r.Return),
+			xlsxReturn(r.Return, units),
+			r.BarCount,
+			xlsxDecimal(r.FirstClose),
+			xlsxDecimal(r.LastClose),
+			r.Name,
+			math.Round(r.AvgVolume),
+			r.ExpectedBars,
+			r.Complete,
+			xlsxDecimal(r.PriceChange),
+			r.UpDays,
+			r.DownDays,
+			r.Baseline,
+			r.BaselineDate,
+		}
+		if cols.excess {
+			row = append(row, xlsxOptionalReturn(r.ExcessReturn, units))
+		}
+		if cols.relative {
+			row = append(row, xlsxOptionalReturn(r.RelativeToSector, units))
+		}
+		if cols.annualized {
+			row = append(row, xlsxOptionalReturn(r.AnnualizedReturn, units), r.AnnualizedCapped)
+		}
+		for _, p := range cols.periods {
+			row = append(row, xlsxOptionalReturn(periodReturn(r, p), units))
+		}
+		if cols.volatility {
+			row = append(row, xlsxOptionalReturn(r.Volatility, units))
+		}
+		if cols.drawdown {
+			row = append(row, xlsxOptionalReturn(r.MaxDrawdown, units))
+		}
+		if cols.currency {
+			row = append(row, r.Currency, xlsxOptionalReturn(r.CurrencyReturn, units))
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(xlsxTickerSheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	// Styles apply per column range, not per cell, so a few calls cover every row
+	last := len(results) + 1
+	if err := styleColumn(f, xlsxTickerSheet, rawCol, last, s.rawReturn); err != nil {
+		return err
+	}
+	if err := styleColumn(f, xlsxTickerSheet, volumeCol, last, s.volume); err != nil {
+		return err
+	}
+	if err := colorBySign(f, xlsxTickerSheet, rawCol, last, s); err != nil {
+		return err
+	}
+	for _, c := range retCols {
+		if err := styleColumn(f, xlsxTickerSheet, c, last, s.ret); err != nil {
+			return err
+		}
+		if err := colorBySign(f, xlsxTickerSheet, c, last, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSectorSheet writes the sector summary rows, then the benchmark below
+// a blank row as in the CSV
+func writeSectorSheet(f *excelize.File, s xlsxStyles, sectorReturns []SectorReturn, units string, benchmark *Benchmark) error {
+	if err := writeXLSXHeader(f, xlsxSectorSheet, s, sectorHeader(units)); err != nil {
+		return err
+	}
+	for i, sr := range sectorReturns {
+		row := []any{sr.Sector, xlsxReturn(sr.AvgReturn, units), sr.TickerCount}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(xlsxSectorSheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	last := len(sectorReturns) + 1
+	if len(sectorReturns) > 0 {
+		if err := styleColumn(f, xlsxSectorSheet, 2, last, s.ret); err != nil {
+			return err
+		}
+		if err := colorBySign(f, xlsxSectorSheet, 2, last, s); err != nil {
+			return err
+		}
+	}
+	if benchmark == nil {
+		return nil
+	}
+
+	header := benchmarkHeader(units)
+	top, _ := excelize.CoordinatesToCellName(1, last+2)
+	if err := f.SetSheetRow(xlsxSectorSheet, top, &header); err != nil {
+		return err
+	}
+	end, _ := excelize.CoordinatesToCellName(len(header), last+2)
+	if err := f.SetCellStyle(xlsxSectorSheet, top, end, s.header); err != nil {
+		return err
+	}
+	row := []any{benchmark.Ticker, xlsxNumber(benchmark.Return), xlsxReturn(benchmark.Return, units)}
+	cell, _ := excelize.CoordinatesToCellName(1, last+3)
+	if err := f.SetSheetRow(xlsxSectorSheet, cell, &row); err != nil {
+		return err
+	}
+	raw, _ := excelize.CoordinatesToCellName(2, last+3)
+	if err := f.SetCellStyle(xlsxSectorSheet, raw, raw, s.rawReturn); err != nil {
+		return err
+	}
+	ret, _ := excelize.CoordinatesToCellName(3, last+3)
+	if err := f.SetCellStyle(xlsxSectorSheet, ret, ret, s.ret); err != nil {
+		return err
+	}
+	return f.SetConditionalFormat(xlsxSectorSheet, raw+":"+ret, s.bySign())
+}
+
+// writeXLSXHeader writes a bold header row and freezes it above the data
+func writeXLSXHeader(f *excelize.File, sheet string, s xlsxStyles, header []string) error {
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+	end, _ := excelize.CoordinatesToCellName(len(header), 1)
+	if err := f.SetCellStyle(sheet, "A1", end, s.header); err != nil {
+		return err
+	}
+	return f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// styleColumn applies a style to rows 2 through last of a column
+func styleColumn(f *excelize.File, sheet string, col, last, style int) error {
+	top, _ := excelize.CoordinatesToCellName(col, 2)
+	bottom, _ := excelize.CoordinatesToCellName(col, last)
+	return f.SetCellStyle(sheet, top, bottom, style)
+}
+
+// colorBySign colors a column's gains green and losses red
+func colorBySign(f *excelize.File, sheet string, col, last int, s xlsxStyles) error {
+	top, _ := excelize.CoordinatesToCellName(col, 2)
+	bottom, _ := excelize.CoordinatesToCellName(col, last)
+	return f.SetConditionalFormat(sheet, top+":"+bottom, s.bySign())
+}
+
+// bySign returns the conditional formats that color gains and losses
+func (s xlsxStyles) bySign() []excelize.ConditionalFormatOptions {
+	return []excelize.ConditionalFormatOptions{
+		{Type: "cell", Criteria: ">", Value: "0", Format: &s.gain},
+		{Type: "cell", Criteria: "<", Value: "0", Format: &s.loss},
+	}
+}
+
+// xlsxNumber returns v for a numeric cell, or nil for an empty one when v is
+// NaN or infinite, matching the CSV's blank
+func xlsxNumber(v float64) any {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return v
+}
+
+// xlsxReturn returns a fractional return in the cell units: the fraction for
+// percent cells, or rounded basis points
+func xlsxReturn(v float64, units string) any {
+	if units == unitsBps {
+		return xlsxNumber(math.Round(v * 10000))
+	}
+	return xlsxNumber(v)
+}
+
+// xlsxOptionalReturn is xlsxReturn for a return that may not have been computed
+func xlsxOptionalReturn(v *float64, units string) any {
+	if v == nil {
+		return nil
+	}
+	return xlsxReturn(*v, units)
+}
+
+// xlsxDecimal writes a decimal price string as a number, keeping the text
+// when it doesn't parse
+func xlsxDecimal(s string) any {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v
+	}
+	return s
+}